@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/fasthttp/websocket"
@@ -18,6 +25,17 @@ import (
 	"github.com/trinhdaiphuc/social-network/config"
 	"github.com/trinhdaiphuc/social-network/graph"
 	"github.com/trinhdaiphuc/social-network/graph/generated"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/comment"
+	"github.com/trinhdaiphuc/social-network/pkg/persisted"
+	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"github.com/trinhdaiphuc/social-network/pkg/ratelimit"
+	"github.com/trinhdaiphuc/social-network/pkg/session"
+	"github.com/trinhdaiphuc/social-network/pkg/storage"
+	"github.com/trinhdaiphuc/social-network/pkg/watcher"
+	"github.com/trinhdaiphuc/social-network/tools"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -25,12 +43,19 @@ import (
 const (
 	defaultPort  = "8080"
 	databaseName = "social-network"
+	// room for the operations and the headers of a multipart request
+	multipartOverhead = 1 << 20
 )
 
 var upgrader = websocket.FastHTTPUpgrader{}
 
+// untrustedProxyOnce warns once about a proxy in front of the server when
+// TRUST_PROXY is not set.
+var untrustedProxyOnce sync.Once
+
 func DatabaseConnection(ctx context.Context) (*mongo.Database, error) {
-	ctx, _ = context.WithTimeout(ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.GetConfig().MongoURI))
 	if err != nil {
 		return nil, err
@@ -39,17 +64,80 @@ func DatabaseConnection(ctx context.Context) (*mongo.Database, error) {
 	return db, nil
 }
 
-func InitGraphQL(db *mongo.Database) (*handler.Server, http.HandlerFunc) {
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(
+func InitGraphQL(resolver *graph.Resolver) (*handler.Server, http.HandlerFunc) {
+	srv := handler.New(generated.NewExecutableSchema(
 		generated.Config{
-			Resolvers: graph.NewResolver(db),
+			Resolvers:  resolver,
+			Directives: graph.NewDirectiveRoot(),
+			Complexity: graph.NewComplexityRoot(),
 		},
 	))
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              websocketInit,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	// a request holds at most the attachments of a post, files beyond one
+	// are buffered on disk
+	maxUploadSize := int64(config.GetConfig().MaxUploadSize)
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: maxUploadSize*post.MaxAttachments + multipartOverhead,
+		MaxMemory:     maxUploadSize,
+	})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+
+	if config.GetConfig().PersistedQueriesOnly {
+		manifest, err := persisted.LoadManifest(config.GetConfig().PersistedQueryManifest)
+		if err != nil {
+			resolver.Logger.Fatalf("Load persisted query manifest error %#v", err)
+		}
+		srv.Use(persisted.Allowlist{Manifest: manifest})
+	} else {
+		srv.Use(extension.AutomaticPersistedQuery{Cache: newPersistedQueryCache(resolver)})
+	}
+
+	if depth := config.GetConfig().MaxQueryDepth; depth > 0 {
+		srv.Use(graph.DepthLimit{Limit: depth})
+	}
+	complexity := config.GetConfig().MaxQueryComplexity
+	if complexity <= 0 {
+		// still compute the cost to report it
+		complexity = math.MaxInt32
+	}
+	srv.Use(extension.FixedComplexityLimit(complexity))
+	srv.AroundResponses(graph.CostMiddleware)
+	srv.SetErrorPresenter(graph.NewErrorPresenter(resolver.Logger))
+	srv.AroundOperations(graph.LoaderMiddleware)
+	srv.AroundFields(graph.RateLimitMiddleware(newRateLimiter(resolver)))
 
 	playground := playground.Handler("GraphQL playground", "/query")
 	return srv, playground
 }
 
+func newPersistedQueryCache(resolver *graph.Resolver) graphql.Cache {
+	cfg := config.GetConfig()
+	if cfg.PersistedQueryCache == "redis" {
+		return persisted.NewRedisCache(resolver.RedisPool, persisted.DefaultRedisPrefix, persisted.DefaultTTL, resolver.Logger)
+	}
+	return persisted.NewMemoryCache(cfg.PersistedQueryCacheSize)
+}
+
+func newRateLimiter(resolver *graph.Resolver) *ratelimit.Limiter {
+	cfg := config.GetConfig()
+	policies, err := ratelimit.ParsePolicies(cfg.RateLimits)
+	if err != nil {
+		resolver.Logger.Fatalf("Parse rate limits error %#v", err)
+	}
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "redis" {
+		store = ratelimit.NewRedisStore(resolver.RedisPool, ratelimit.DefaultRedisPrefix)
+	}
+	return ratelimit.NewLimiter(store, policies, resolver.Logger)
+}
+
 func AllowCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if origin := r.Header.Get("Origin"); origin != "" {
@@ -74,33 +162,95 @@ func preflightHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposeHeaders, ","))
 }
 
+// clientIPMiddleware puts the IP address of the client in the context. Behind
+// a trusted proxy it is the last address of X-Forwarded-For, the one the
+// proxy added, the others are sent by the client and may be forged.
+func clientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		forwarded := r.Header.Get("X-Forwarded-For")
+		if forwarded != "" && config.GetConfig().TrustProxy {
+			addresses := strings.Split(forwarded, ",")
+			ip = strings.TrimSpace(addresses[len(addresses)-1])
+		} else if forwarded != "" {
+			untrustedProxyOnce.Do(func() {
+				log.Printf("Requests are forwarded by %s but TRUST_PROXY is not set, all its clients share "+
+					"its IP address in the rate limits and the login locks", ip)
+			})
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "clientIP", ip)))
+	})
+}
+
 func jwtMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if refreshToken := r.Header.Get("Refresh-Token"); refreshToken != "" {
+			r = r.WithContext(context.WithValue(r.Context(), "refreshToken", refreshToken))
+		}
+
 		authHeader := strings.Split(r.Header.Get("Authorization"), "Bearer ")
 		if len(authHeader) == 2 {
-			jwtToken := authHeader[1]
-			token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(config.GetConfig().JwtKey), nil
-			})
-
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				ctx := context.WithValue(r.Context(), "user", claims)
-				next.ServeHTTP(w, r.WithContext(ctx))
-				return
-			} else {
-				fmt.Println(err)
+			ctx, err := authenticate(r.Context(), authHeader[1])
+			if err != nil {
 				w.WriteHeader(http.StatusUnauthorized)
-				w.Write([]byte("Unauthorized"))
+				w.Write([]byte(err.Error()))
+				return
 			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// websocketInit authenticates subscriptions with the token sent in the
+// connection_init payload, browsers can't set headers on the upgrade request.
+// The token is the Authorization value or authToken, with or without Bearer.
+func websocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, error) {
+	token := payload.Authorization()
+	if token == "" {
+		token = payload.GetString("authToken")
+	}
+	token = strings.TrimPrefix(token, "Bearer ")
+	if token == "" {
+		return ctx, nil
+	}
+	return authenticate(ctx, token)
+}
+
+// authenticate puts the user of a valid access token of an active session
+// in the context.
+func authenticate(ctx context.Context, jwtToken string) (context.Context, error) {
+	token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.GetConfig().JwtKey), nil
+	})
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		fmt.Println(err)
+		return nil, apperrors.Unauthenticated("Unauthorized")
+	}
+
+	ctx = context.WithValue(ctx, "user", claims)
+	// Reject access tokens of sessions that were logged out
+	sid, err := tools.ForSessionContext(ctx)
+	if err != nil {
+		return nil, apperrors.Unauthenticated("Unauthorized")
+	}
+	active, err := session.GetSessionRepository().IsActive(ctx, sid)
+	if err != nil || !active {
+		return nil, apperrors.Unauthenticated("Session revoked")
+	}
+	return ctx, nil
+}
+
 func main() {
 	godotenv.Load()
 	config.Load()
@@ -115,10 +265,29 @@ func main() {
 		panic(err)
 	}
 
-	gqlHandler, playground := InitGraphQL(db)
+	if _, err := comment.MigrateEmbeddedComments(mongoCtx, db, logger.NewAppLog()); err != nil {
+		panic(err)
+	}
+
+	resolver := graph.NewResolver(db)
+	if username := config.GetConfig().AdminUsername; username != "" {
+		if _, err := resolver.UserService.EnsureAdmin(mongoCtx, username); err != nil {
+			resolver.Logger.Warnf("Make %s an admin error: register the user then restart, %v", username, err)
+		}
+	}
+	gqlHandler, playground := InitGraphQL(resolver)
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	if config.GetConfig().WatchPosts {
+		go watcher.NewPostWatcher(db, resolver.Broker, resolver.Logger).Run(watchCtx)
+	}
 
 	http.Handle("/", playground)
-	http.Handle("/query", AllowCORS(jwtMiddleware(gqlHandler)))
+	http.Handle("/query", AllowCORS(clientIPMiddleware(jwtMiddleware(gqlHandler))))
+	http.Handle("/metrics/pubsub", pubsub.MetricsHandler(resolver.Broker))
+	if local, ok := resolver.Storage.(*storage.LocalStorage); ok {
+		http.Handle(storage.LocalPrefix, local.Handler())
+	}
 
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
 	// Listen from a different goroutine
@@ -128,11 +297,19 @@ func main() {
 		}
 	}()
 
-	c := make(chan os.Signal)      // Create channel to signify a signal being sent
+	c := make(chan os.Signal, 1)   // Create channel to signify a signal being sent
 	signal.Notify(c, os.Interrupt) // When an interrupt is sent, notify the channel
 
 	<-c // This blocks the main thread until an interrupt is received
 	fmt.Println("Gracefully shutting down...")
+	fmt.Println("Stop watching posts")
+	stopWatching()
+	fmt.Println("Close pub/sub broker")
+	resolver.Broker.Close()
+	if resolver.RedisPool != nil {
+		fmt.Println("Close Redis connections")
+		resolver.RedisPool.Close()
+	}
 	fmt.Println("Close DB connection")
 	db.Client().Disconnect(mongoCtx)
 
@@ -7,20 +7,106 @@ type Config struct {
 	LogLevel string
 	LogPath  string
 	MongoURI string
+	// Token lifetimes in minutes
+	AccessTokenExpiry  int
+	RefreshTokenExpiry int
+	// Subscriber buffer size and what to do when it is full:
+	// drop-oldest, drop-newest or disconnect
+	PubSubBufferSize int
+	PubSubPolicy     string
+	// memory or redis, redis delivers events to subscribers of all replicas
+	PubSubDriver string
+	RedisURL     string
+	// Publish post events from a MongoDB change stream instead of the services
+	WatchPosts bool
+	// Names the change stream position of this replica, the hostname by default
+	WatcherID string
+	// local or s3, where post attachments are stored
+	StorageDriver  string
+	StoragePath    string
+	StorageBaseURL string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PathStyle    bool
+	// Maximum size of an uploaded file in bytes
+	MaxUploadSize int
+	// Maximum width times height of an uploaded image
+	MaxImagePixels int
+	// Limits of the GraphQL operations, 0 disables a limit
+	MaxQueryDepth      int
+	MaxQueryComplexity int
+	// memory or redis, where automatic persisted queries are kept
+	PersistedQueryCache     string
+	PersistedQueryCacheSize int
+	// Only execute the operations of the manifest generated from the web client
+	PersistedQueriesOnly   bool
+	PersistedQueryManifest string
+	// memory or redis, where the rate limit buckets are kept
+	RateLimitStore string
+	// operation=count/period separated by commas, e.g. createPost=10/1m
+	RateLimits string
+	// Take the client IP from X-Forwarded-For, set behind a reverse proxy
+	TrustProxy bool
+	// Failed logins before a username or an IP address is locked, and
+	// for how many minutes
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	LoginLockout          int
+	// Registered user made ADMIN at startup, to create the first admin
+	AdminUsername string
 }
 
+// DefaultRateLimits limits the mutations creating content or trying passwords.
+const DefaultRateLimits = "createPost=10/1m,createComment=30/1m,likePost=60/1m,unlikePost=60/1m," +
+	"reactToPost=60/1m,reactToComment=60/1m,uploadImage=20/1m,followUser=30/1m,login=10/1m,register=5/1h"
+
 var (
 	configValue Config
 )
 
 func Load() Config {
 	configValue = Config{
-		Port:     env("PORT", "8080"),
-		JwtKey:   env("JWT_KEY", "secret"),
-		Env:      env("ENV", "local"),
-		LogLevel: env("LOG_LEVEL", "INFO"),
-		LogPath:  env("LOG_PATH", ""),
-		MongoURI: env("DB_URI", "mongodb://localhost/social-network"),
+		Port:                    env("PORT", "8080"),
+		JwtKey:                  env("JWT_KEY", "secret"),
+		Env:                     env("ENV", "local"),
+		LogLevel:                env("LOG_LEVEL", "INFO"),
+		LogPath:                 env("LOG_PATH", ""),
+		MongoURI:                env("DB_URI", "mongodb://localhost/social-network"),
+		AccessTokenExpiry:       envInt("ACCESS_TOKEN_EXPIRY", 15),
+		RefreshTokenExpiry:      envInt("REFRESH_TOKEN_EXPIRY", 30*24*60),
+		PubSubBufferSize:        envInt("PUBSUB_BUFFER_SIZE", 16),
+		PubSubPolicy:            env("PUBSUB_POLICY", "drop-oldest"),
+		PubSubDriver:            env("PUBSUB_DRIVER", "memory"),
+		RedisURL:                env("REDIS_URL", "redis://localhost:6379"),
+		WatchPosts:              envBool("WATCH_POSTS", false),
+		WatcherID:               env("WATCHER_ID", ""),
+		StorageDriver:           env("STORAGE_DRIVER", "local"),
+		StoragePath:             env("STORAGE_PATH", "uploads"),
+		StorageBaseURL:          env("STORAGE_BASE_URL", ""),
+		S3Endpoint:              env("S3_ENDPOINT", ""),
+		S3Region:                env("S3_REGION", "us-east-1"),
+		S3Bucket:                env("S3_BUCKET", ""),
+		S3AccessKey:             env("S3_ACCESS_KEY", ""),
+		S3SecretKey:             env("S3_SECRET_KEY", ""),
+		S3PathStyle:             envBool("S3_PATH_STYLE", false),
+		MaxUploadSize:           envInt("MAX_UPLOAD_SIZE", 10<<20),
+		MaxImagePixels:          envInt("MAX_IMAGE_PIXELS", 25000000),
+		MaxQueryDepth:           envInt("MAX_QUERY_DEPTH", 10),
+		MaxQueryComplexity:      envInt("MAX_QUERY_COMPLEXITY", 2000),
+		PersistedQueryCache:     env("PERSISTED_QUERY_CACHE", "memory"),
+		PersistedQueryCacheSize: envInt("PERSISTED_QUERY_CACHE_SIZE", 1000),
+		PersistedQueriesOnly:    envBool("PERSISTED_QUERIES_ONLY", false),
+		PersistedQueryManifest:  env("PERSISTED_QUERY_MANIFEST", "web/persisted-queries.json"),
+		RateLimitStore:          env("RATE_LIMIT_STORE", "memory"),
+		RateLimits:              env("RATE_LIMITS", DefaultRateLimits),
+		TrustProxy:              envBool("TRUST_PROXY", false),
+		LoginMaxAttempts:        envInt("LOGIN_MAX_ATTEMPTS", 10),
+		LoginMaxAttemptsPerIP:   envInt("LOGIN_MAX_ATTEMPTS_PER_IP", 100),
+		LoginLockout:            envInt("LOGIN_LOCKOUT", 15),
+		AdminUsername:           env("ADMIN_USERNAME", ""),
 	}
 	return configValue
 }
@@ -1,10 +1,29 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 func env(key, defaultValue string) (value string) {
 	if value = os.Getenv(key); value == "" {
 		value = defaultValue
 	}
 	return
-}
\ No newline at end of file
+}
+
+func envBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"math"
+
+	"github.com/trinhdaiphuc/social-network/graph/generated"
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/search"
+)
+
+// listSize is the assumed length of the lists that no argument bounds, such
+// as the likes of a post.
+const listSize = internal.MaxPageSize
+
+// NewComplexityRoot estimates the cost of the fields returning lists as the
+// cost of one element times the number of elements they may return. Other
+// fields cost 1 plus the cost of their selection.
+func NewComplexityRoot() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.GetPosts = func(child int, first *int, after *string, last *int, before *string) int {
+		return pageCost(child, first, last)
+	}
+	c.Query.HomeFeed = func(child int, first *int, after *string, last *int, before *string) int {
+		return pageCost(child, first, last)
+	}
+	c.Query.PostsByTag = func(child int, tag string, first *int, after *string, last *int, before *string) int {
+		return pageCost(child, first, last)
+	}
+	c.Query.SearchPosts = func(child int, query string, first *int, after *string) int {
+		return pageCost(child, first, nil)
+	}
+	c.Query.Notifications = func(child int, first *int, after *string, unreadOnly *bool) int {
+		return pageCost(child, first, nil)
+	}
+	c.Query.GetUsers = func(child int) int { return listCost(child, listSize) }
+	c.Query.SearchUsers = func(child int, query string) int { return listCost(child, search.UserLimit) }
+	c.Query.TrendingTags = func(child int, window *int) int { return listCost(child, post.TrendingTagsLimit) }
+
+	c.Post.Comments = func(child int, first *int, after *string) int {
+		return pageCost(child, first, nil)
+	}
+	c.Post.Likes = func(child int) int { return listCost(child, listSize) }
+	c.Post.Revisions = func(child int) int { return listCost(child, listSize) }
+	c.Post.Attachments = func(child int) int { return listCost(child, post.MaxAttachments) }
+	c.Post.Images = func(child int) int { return listCost(child, post.MaxImages) }
+
+	c.Comment.Replies = func(child int) int { return listCost(child, listSize) }
+	c.Comment.Likes = func(child int) int { return listCost(child, listSize) }
+	c.Comment.Revisions = func(child int) int { return listCost(child, listSize) }
+
+	c.User.Followers = func(child int, first *int, after *string, last *int, before *string) int {
+		return pageCost(child, first, last)
+	}
+	c.User.Following = func(child int, first *int, after *string, last *int, before *string) int {
+		return pageCost(child, first, last)
+	}
+
+	return c
+}
+
+// pageCost is the cost of a connection returning first or last elements.
+func pageCost(child int, first *int, last *int) int {
+	size := internal.DefaultPageSize
+	if first != nil {
+		size = *first
+	} else if last != nil {
+		size = *last
+	}
+	if size > internal.MaxPageSize {
+		size = internal.MaxPageSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	return listCost(child, size)
+}
+
+// listCost saturates instead of overflowing on deeply nested lists.
+func listCost(child int, size int) int {
+	if size > 0 && child > math.MaxInt32/size {
+		return math.MaxInt32
+	}
+	return 1 + child*size
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"math"
+	"testing"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/trinhdaiphuc/social-network/internal"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestPageCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		first *int
+		last  *int
+		want  int
+	}{
+		{"default page", nil, nil, 1 + 3*internal.DefaultPageSize},
+		{"first", intPtr(20), nil, 1 + 3*20},
+		{"last", nil, intPtr(5), 1 + 3*5},
+		{"first over last", intPtr(2), intPtr(40), 1 + 3*2},
+		{"over the max page", intPtr(1000), nil, 1 + 3*internal.MaxPageSize},
+		{"negative", intPtr(-5), nil, 1 + 3},
+	}
+	for _, tt := range tests {
+		if got := pageCost(3, tt.first, tt.last); got != tt.want {
+			t.Errorf("%s: cost %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListCostSaturates(t *testing.T) {
+	if got := listCost(math.MaxInt32/2, 50); got != math.MaxInt32 {
+		t.Errorf("cost %d, want %d", got, math.MaxInt32)
+	}
+	if got := listCost(0, 50); got != 1 {
+		t.Errorf("cost of an empty selection %d, want 1", got)
+	}
+}
+
+func TestOperationComplexity(t *testing.T) {
+	es := newTestSchema()
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		// node { id } costs 2 and edges 3 per post
+		{"first", `{ getPosts(first: 20) { edges { node { id } } } }`, 1 + 3*20},
+		{"default page", `{ getPosts { edges { node { id } } } }`, 1 + 3*internal.DefaultPageSize},
+		{"capped", `{ getPosts(first: 1000) { edges { node { id } } } }`, 1 + 3*internal.MaxPageSize},
+		// the comments of each post are multiplied by the posts
+		{"nested", `{ getPosts(first: 10) { edges { node { comments(first: 5) { edges { node { id } } } } } } }`,
+			1 + 10*(1+1+(1+5*3))},
+		{"variable", `query ($n: Int) { getPosts(first: $n) { edges { node { id } } } }`, 1 + 3*4},
+	}
+	for _, tt := range tests {
+		doc := parseQuery(t, es, tt.query)
+		got := complexity.Calculate(es, doc.Operations[0], map[string]interface{}{"n": 4})
+		if got != tt.want {
+			t.Errorf("%s: complexity %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trinhdaiphuc/social-network/graph/generated"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/tools"
+)
+
+// NewDirectiveRoot implements the directives declared in the schema.
+func NewDirectiveRoot() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		Auth:    Auth,
+		HasRole: HasRole,
+	}
+}
+
+// Auth only resolves the field for authenticated users.
+func Auth(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if user, err := tools.ForUserContext(ctx); user == nil || err != nil {
+		return nil, apperrors.Unauthenticated("Unauthorize")
+	}
+	return next(ctx)
+}
+
+// HasRole only resolves the field for users having the role or a higher one.
+func HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role models.Role) (interface{}, error) {
+	user, err := tools.ForUserContext(ctx)
+	if user == nil || err != nil {
+		return nil, apperrors.Unauthenticated("Unauthorize")
+	}
+	if !user.HasRole(role) {
+		return nil, apperrors.Forbidden("Action not allowed")
+	}
+	return next(ctx)
+}
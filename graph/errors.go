@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// NewErrorPresenter tags application errors with their code in the
+// extensions so clients can switch on it. Errors of gqlgen itself such as
+// validation errors are kept as they are, any other error may leak internal
+// details, it is logged and only shown as an internal error.
+func NewErrorPresenter(log *logger.AppLog) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) {
+			if appErr.Err != nil {
+				log.Errorf("%s error %#v", appErr.Code, appErr.Err)
+			}
+			gqlErr.Message = appErr.Message
+			setCode(gqlErr, appErr.Code)
+			for key, value := range appErr.Extensions {
+				gqlErr.Extensions[key] = value
+			}
+			return gqlErr
+		}
+		if errors.Unwrap(gqlErr) == nil {
+			return gqlErr
+		}
+
+		log.Errorf("Internal error %#v", err)
+		gqlErr.Message = "Internal server error"
+		setCode(gqlErr, apperrors.CodeInternal)
+		return gqlErr
+	}
+}
+
+func setCode(err *gqlerror.Error, code apperrors.Code) {
+	if err.Extensions == nil {
+		err.Extensions = map[string]interface{}{}
+	}
+	err.Extensions["code"] = code
+}
@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// DepthLimit rejects the operations selecting fields nested deeper than
+// Limit. Introspection fields are not counted, the introspection query of
+// the playground is deeply nested but cheap.
+type DepthLimit struct {
+	Limit int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(schema graphql.ExecutableSchema) error {
+	if d.Limit <= 0 {
+		return fmt.Errorf("DepthLimit limit must be positive")
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op == nil {
+		return nil
+	}
+	if depth := selectionDepth(op.SelectionSet); depth > d.Limit {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Limit)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// selectionDepth is the number of nested fields of the deepest branch of set.
+// Fragment cycles are rejected by the validation before.
+func selectionDepth(set ast.SelectionSet) int {
+	max := 0
+	for _, selection := range set {
+		depth := 0
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name, "__") {
+				continue
+			}
+			depth = 1 + selectionDepth(selection.SelectionSet)
+		case *ast.InlineFragment:
+			depth = selectionDepth(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if selection.Definition != nil {
+				depth = selectionDepth(selection.Definition.SelectionSet)
+			}
+		}
+		if depth > max {
+			max = depth
+		}
+	}
+	return max
+}
+
+// CostMiddleware returns the complexity of the operation and its limit in
+// the "cost" extension of the response.
+func CostMiddleware(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		return nil
+	}
+	if stats := extension.GetComplexityStats(ctx); stats != nil {
+		if resp.Extensions == nil {
+			resp.Extensions = map[string]interface{}{}
+		}
+		resp.Extensions["cost"] = map[string]int{
+			"complexity": stats.Complexity,
+			"limit":      stats.ComplexityLimit,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trinhdaiphuc/social-network/graph/generated"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func newTestSchema() graphql.ExecutableSchema {
+	return generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{},
+		Directives: NewDirectiveRoot(),
+		Complexity: NewComplexityRoot(),
+	})
+}
+
+// parseQuery parses and validates query against the schema of the server.
+func parseQuery(t *testing.T, es graphql.ExecutableSchema, query string) *ast.QueryDocument {
+	t.Helper()
+	doc, errs := gqlparser.LoadQuery(es.Schema(), query)
+	if errs != nil {
+		t.Fatalf("parse %s: %v", query, errs)
+	}
+	return doc
+}
+
+func TestSelectionDepth(t *testing.T) {
+	es := newTestSchema()
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"flat", `{ getUsers { username } }`, 2},
+		{"nested connection", `{ getPosts { edges { node { comments { edges { node { body } } } } } } }`, 7},
+		{"deepest branch", `{ getPosts { pageInfo { hasNextPage } edges { node { author { username } } } } }`, 5},
+		{"inline fragment", `{ getPosts { edges { ... on PostEdge { node { id } } } } }`, 4},
+		{"fragment spread", `{ getPosts { edges { ...edge } } } fragment edge on PostEdge { node { author { username } } }`, 5},
+		{"introspection", `{ __schema { types { fields { type { ofType { name } } } } } }`, 0},
+		{"typename", `{ getUsers { __typename } }`, 1},
+	}
+	for _, tt := range tests {
+		doc := parseQuery(t, es, tt.query)
+		if got := selectionDepth(doc.Operations[0].SelectionSet); got != tt.want {
+			t.Errorf("%s: depth %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	es := newTestSchema()
+	// depth 5
+	query := `query Feed { getPosts { edges { node { author { username } } } } }`
+	tests := []struct {
+		limit    int
+		rejected bool
+	}{
+		{4, true},
+		{5, false},
+		{6, false},
+	}
+	for _, tt := range tests {
+		rc := &graphql.OperationContext{Doc: parseQuery(t, es, query), OperationName: "Feed"}
+		err := DepthLimit{Limit: tt.limit}.MutateOperationContext(context.Background(), rc)
+		if rejected := err != nil; rejected != tt.rejected {
+			t.Errorf("limit %d: error %v, want rejected %t", tt.limit, err, tt.rejected)
+			continue
+		}
+		if err != nil && err.Extensions["code"] != errDepthLimit {
+			t.Errorf("limit %d: code %v, want %s", tt.limit, err.Extensions["code"], errDepthLimit)
+		}
+	}
+}
+
+func TestDepthLimitValidate(t *testing.T) {
+	if err := (DepthLimit{}).Validate(newTestSchema()); err == nil {
+		t.Error("accepted a limit of 0")
+	}
+}
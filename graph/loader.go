@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/user"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// LoaderMiddleware gives every query and mutation its own user loader.
+// Subscriptions get none, their operation lasts as long as the connection so
+// the loader would keep stale users.
+func LoaderMiddleware(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if op := graphql.GetOperationContext(ctx).Operation; op != nil && op.Operation != ast.Subscription {
+		ctx = user.WithUserLoader(ctx, user.NewUserLoader(user.GetUserRepository()))
+	}
+	return next(ctx)
+}
+
+// loadUser finds a user through the loader of the operation when it has one.
+func loadUser(ctx context.Context, username string) (*models.User, error) {
+	if loader := user.ForLoaderContext(ctx); loader != nil {
+		return loader.Load(ctx, username)
+	}
+	users, err := user.GetUserRepository().GetListByUsernames(ctx, []string{username})
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return users[0], nil
+}
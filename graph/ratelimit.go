@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trinhdaiphuc/social-network/pkg/ratelimit"
+	"github.com/trinhdaiphuc/social-network/tools"
+)
+
+// RateLimitMiddleware limits the mutations having a policy, per user or per
+// client IP for anonymous calls such as login.
+func RateLimitMiddleware(limiter *ratelimit.Limiter) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc.Object == "Mutation" {
+			if err := limiter.Allow(ctx, fc.Field.Name, clientKey(ctx)); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx)
+	}
+}
+
+func clientKey(ctx context.Context) string {
+	if user, err := tools.ForUserContext(ctx); err == nil && user.Username != "" {
+		return "user:" + user.Username
+	}
+	return "ip:" + tools.ForClientIPContext(ctx)
+}
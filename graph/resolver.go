@@ -1,10 +1,19 @@
 package graph
 
 import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
 	"github.com/trinhdaiphuc/social-network/pkg/comment"
+	"github.com/trinhdaiphuc/social-network/pkg/follow"
 	"github.com/trinhdaiphuc/social-network/pkg/like"
+	"github.com/trinhdaiphuc/social-network/pkg/media"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
 	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"github.com/trinhdaiphuc/social-network/pkg/search"
+	"github.com/trinhdaiphuc/social-network/pkg/storage"
 	"github.com/trinhdaiphuc/social-network/pkg/user"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -14,22 +23,95 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB             *mongo.Database
-	Logger         *logger.AppLog
-	PostService    post.PostService
-	UserService    user.UserService
-	CommentService comment.CommentService
-	LikeService    like.LikeService
+	DB     *mongo.Database
+	Logger *logger.AppLog
+	Broker pubsub.Broker
+	// RedisPool is shared by everything kept in Redis, nil when nothing is
+	RedisPool           *redis.Pool
+	Storage             storage.Storage
+	PostService         post.PostService
+	UserService         user.UserService
+	CommentService      comment.CommentService
+	LikeService         like.LikeService
+	FollowService       follow.FollowService
+	SearchService       search.SearchService
+	NotificationService notification.NotificationService
+	MediaService        media.MediaService
 }
 
 func NewResolver(db *mongo.Database) *Resolver {
 	logger := logger.NewAppLog()
+	policy, err := pubsub.ParsePolicy(config.GetConfig().PubSubPolicy)
+	if err != nil {
+		logger.Warnf("Use drop-oldest policy: %v", err)
+	}
+	options := pubsub.Options{
+		BufferSize: config.GetConfig().PubSubBufferSize,
+		Policy:     policy,
+	}
+
+	var pool *redis.Pool
+	if usesRedis(config.GetConfig()) {
+		pool, err = internal.NewRedisPool(config.GetConfig().RedisURL)
+		if err != nil {
+			logger.Fatalf("Connect to Redis error %#v", err)
+		}
+	}
+
+	var broker pubsub.Broker = pubsub.NewMemoryBroker(options)
+	if config.GetConfig().PubSubDriver == "redis" {
+		broker = pubsub.NewRedisBroker(pool, pubsub.DefaultRedisPrefix, options, logger)
+	}
+
+	// The post watcher publishes the changes of post documents, the services
+	// must not publish them a second time.
+	postBroker := broker
+	if config.GetConfig().WatchPosts {
+		postBroker = pubsub.WithoutTopics(broker, pubsub.TopicNewPost, pubsub.TopicPostUpdated, pubsub.TopicPostDeleted)
+	}
+
+	store, err := newStorage()
+	if err != nil {
+		logger.Fatalf("Open storage error %#v", err)
+	}
+
+	notifications := notification.NewNotificationService(db, logger, broker)
+	// the post service deletes the comments of deleted posts
+	comments := comment.NewCommentService(db, logger, broker, notifications)
+
 	return &Resolver{
-		DB:             db,
-		Logger:         logger,
-		PostService:    post.NewPostService(db, logger),
-		UserService:    user.NewUserService(db, logger),
-		CommentService: comment.NewCommentService(logger),
-		LikeService:    like.NewLikeService(logger),
+		DB:                  db,
+		Logger:              logger,
+		Broker:              broker,
+		RedisPool:           pool,
+		Storage:             store,
+		PostService:         post.NewPostService(db, logger, postBroker, notifications, store, comment.GetCommentRepository()),
+		UserService:         user.NewUserService(db, logger),
+		CommentService:      comments,
+		LikeService:         like.NewLikeService(logger, postBroker, notifications),
+		FollowService:       follow.NewFollowService(db, logger, notifications),
+		SearchService:       search.NewSearchService(logger),
+		NotificationService: notifications,
+		MediaService:        media.NewMediaService(db, logger, store),
+	}
+}
+
+func usesRedis(cfg config.Config) bool {
+	return cfg.PubSubDriver == "redis" || cfg.PersistedQueryCache == "redis" || cfg.RateLimitStore == "redis"
+}
+
+func newStorage() (storage.Storage, error) {
+	cfg := config.GetConfig()
+	if cfg.StorageDriver == "s3" {
+		return storage.NewS3Storage(storage.S3Options{
+			Endpoint:       cfg.S3Endpoint,
+			Region:         cfg.S3Region,
+			Bucket:         cfg.S3Bucket,
+			AccessKey:      cfg.S3AccessKey,
+			SecretKey:      cfg.S3SecretKey,
+			ForcePathStyle: cfg.S3PathStyle,
+			BaseURL:        cfg.StorageBaseURL,
+		})
 	}
+	return storage.NewLocalStorage(cfg.StoragePath, cfg.StorageBaseURL)
 }
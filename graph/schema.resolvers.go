@@ -5,49 +5,142 @@ package graph
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	"github.com/satori/go.uuid"
-	"github.com/trinhdaiphuc/social-network/common"
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/trinhdaiphuc/social-network/graph/generated"
 	"github.com/trinhdaiphuc/social-network/graph/model"
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"github.com/trinhdaiphuc/social-network/pkg/storage"
 	"github.com/trinhdaiphuc/social-network/tools"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+func (r *attachmentResolver) URL(ctx context.Context, obj *models.Attachment) (string, error) {
+	return r.Storage.URL(obj.Key), nil
+}
+
 func (r *commentResolver) ID(ctx context.Context, obj *models.Comment) (string, error) {
 	return obj.ID.Hex(), nil
 }
 
-func (r *likeResolver) ID(ctx context.Context, obj *models.Like) (string, error) {
+func (r *commentResolver) Author(ctx context.Context, obj *models.Comment) (*models.User, error) {
+	if obj.Deleted {
+		return nil, nil
+	}
+	return loadUser(ctx, obj.Username)
+}
+
+func (r *commentResolver) ParentID(ctx context.Context, obj *models.Comment) (*string, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	id := obj.ParentID.Hex()
+	return &id, nil
+}
+
+func (r *commentResolver) Replies(ctx context.Context, obj *models.Comment) ([]*models.Comment, error) {
+	return r.CommentService.GetReplies(ctx, obj.ID)
+}
+
+func (r *commentResolver) LikeCount(ctx context.Context, obj *models.Comment) (int, error) {
+	return len(obj.Likes), nil
+}
+
+func (r *commentResolver) ReactionCounts(ctx context.Context, obj *models.Comment) ([]*models.ReactionCount, error) {
+	return models.CountReactions(obj.Likes), nil
+}
+
+func (r *imageResolver) ID(ctx context.Context, obj *models.Image) (string, error) {
 	return obj.ID.Hex(), nil
 }
 
-func (r *mutationResolver) CreatePost(ctx context.Context, body string) (*models.Post, error) {
-	user, err := tools.ForUserContext(ctx)
-	if user == nil || err != nil {
-		return nil, fmt.Errorf("Unauthorize")
+func (r *imageResolver) URL(ctx context.Context, obj *models.Image, size *models.ImageSize) (string, error) {
+	variant := obj.Variant(models.ImageOriginal)
+	if size != nil {
+		variant = obj.Variant(*size)
+	}
+	if variant == nil {
+		return "", apperrors.NotFound("Not found image")
 	}
+	return r.Storage.URL(variant.Key), nil
+}
+
+func (r *imageVariantResolver) URL(ctx context.Context, obj *models.ImageVariant) (string, error) {
+	return r.Storage.URL(obj.Key), nil
+}
+
+func (r *likeResolver) ID(ctx context.Context, obj *models.Like) (string, error) {
+	return obj.ID.Hex(), nil
+}
+
+func (r *likeResolver) User(ctx context.Context, obj *models.Like) (*models.User, error) {
+	return loadUser(ctx, obj.Username)
+}
+
+func (r *mutationResolver) CreatePost(ctx context.Context, body string, attachments []*graphql.Upload, images []string) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
 	newPost := &models.Post{
 		Body:      body,
 		CreatedAt: time.Now().Format(time.RFC3339),
 		Username:  user.Username,
 	}
-	newPost, err = r.PostService.CreatePost(ctx, newPost)
+	for _, id := range images {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, apperrors.Validation("Invalid image id")
+		}
+		newPost.ImageIDs = append(newPost.ImageIDs, oid)
+	}
+	files := make([]*storage.File, len(attachments))
+	for i, upload := range attachments {
+		files[i] = &storage.File{
+			Filename:    upload.Filename,
+			ContentType: upload.ContentType,
+			Size:        upload.Size,
+			Content:     upload.File,
+		}
+	}
+	newPost, err := r.PostService.CreatePost(ctx, newPost, files)
 	if err != nil {
 		return nil, err
 	}
 	return newPost, nil
 }
 
+func (r *mutationResolver) UploadImage(ctx context.Context, file graphql.Upload) (*models.Image, error) {
+	user, _ := tools.ForUserContext(ctx)
+	return r.MediaService.UploadImage(ctx, user.Username, &storage.File{
+		Filename:    file.Filename,
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		Content:     file.File,
+	})
+}
+
 func (r *mutationResolver) DeletePost(ctx context.Context, id string) (string, error) {
+	user, _ := tools.ForUserContext(ctx)
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return "", fmt.Errorf("Invalid id")
+		return "", apperrors.Validation("Invalid id")
+	}
+	result, err := r.PostService.DeletePost(ctx, oid, user)
+	if err != nil {
+		return "", err
 	}
-	return r.PostService.DeletePost(ctx, oid)
+	return result, nil
+}
+
+func (r *mutationResolver) EditPost(ctx context.Context, postID string, body string) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+	return r.PostService.EditPost(ctx, postOID, user.Username, body)
 }
 
 func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*models.User, error) {
@@ -55,7 +148,7 @@ func (r *mutationResolver) Login(ctx context.Context, username string, password
 		Username: username,
 		Password: password,
 	}
-	user, err := r.UserService.Login(ctx, user)
+	user, err := r.UserService.Login(ctx, user, tools.ForClientIPContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +157,7 @@ func (r *mutationResolver) Login(ctx context.Context, username string, password
 
 func (r *mutationResolver) Register(ctx context.Context, registerInput model.RegisterInput) (*models.User, error) {
 	if registerInput.Password != registerInput.ConfirmPassword {
-		return nil, fmt.Errorf("password and confirm password is not match")
+		return nil, apperrors.Validation("password and confirm password is not match")
 	}
 	newUser := &models.User{
 		Email:     registerInput.Email,
@@ -79,11 +172,35 @@ func (r *mutationResolver) Register(ctx context.Context, registerInput model.Reg
 	return newUser, nil
 }
 
-func (r *mutationResolver) CreateComment(ctx context.Context, postID string, body string) (*models.Post, error) {
-	user, err := tools.ForUserContext(ctx)
-	if user == nil || err != nil {
-		return nil, fmt.Errorf("Unauthorize")
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken *string) (*models.User, error) {
+	if refreshToken == nil {
+		token, err := tools.ForRefreshTokenContext(ctx)
+		if err != nil {
+			return nil, apperrors.Unauthenticated("Missing refresh token")
+		}
+		refreshToken = &token
 	}
+	return r.UserService.RefreshToken(ctx, *refreshToken)
+}
+
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	sid, err := tools.ForSessionContext(ctx)
+	if err != nil {
+		return false, apperrors.Unauthenticated("Unauthorize")
+	}
+	if err := r.UserService.Logout(ctx, sid); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (int, error) {
+	user, _ := tools.ForUserContext(ctx)
+	return r.UserService.LogoutAllSessions(ctx, user.ID)
+}
+
+func (r *mutationResolver) CreateComment(ctx context.Context, postID string, body string, parentID *string) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
 	comment := &models.Comment{
 		ID:        primitive.NewObjectID(),
 		Username:  user.Username,
@@ -92,64 +209,176 @@ func (r *mutationResolver) CreateComment(ctx context.Context, postID string, bod
 	}
 	postOID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid post id")
+		return nil, apperrors.Validation("Invalid post id")
 	}
-	return r.CommentService.CreateComment(ctx, postOID, comment)
+	var parentOID *primitive.ObjectID
+	if parentID != nil {
+		oid, err := primitive.ObjectIDFromHex(*parentID)
+		if err != nil {
+			return nil, apperrors.Validation("Invalid parent id")
+		}
+		parentOID = &oid
+	}
+	return r.CommentService.CreateComment(ctx, postOID, parentOID, comment)
 }
 
 func (r *mutationResolver) DeleteComment(ctx context.Context, postID string, commentID string) (*models.Post, error) {
-	user, err := tools.ForUserContext(ctx)
-	if user == nil || err != nil {
-		return nil, fmt.Errorf("Unauthorize")
-	}
+	user, _ := tools.ForUserContext(ctx)
 	postOID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid post id")
+		return nil, apperrors.Validation("Invalid post id")
 	}
 	commentOID, err := primitive.ObjectIDFromHex(commentID)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid comment id")
+		return nil, apperrors.Validation("Invalid comment id")
 	}
-	return r.CommentService.DeleteComment(ctx, postOID, commentOID, user.Username)
+	return r.CommentService.DeleteComment(ctx, postOID, commentOID, user)
 }
 
-func (r *mutationResolver) LikePost(ctx context.Context, postID string) (*models.Post, error) {
-	user, err := tools.ForUserContext(ctx)
-	if user == nil || err != nil {
-		return nil, fmt.Errorf("Unauthorize")
+func (r *mutationResolver) EditComment(ctx context.Context, commentID string, body string) (*models.Comment, error) {
+	user, _ := tools.ForUserContext(ctx)
+	commentOID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid comment id")
 	}
+	return r.CommentService.EditComment(ctx, commentOID, user.Username, body)
+}
+
+func (r *mutationResolver) LikePost(ctx context.Context, postID string) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
 	postOID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid post id")
+		return nil, apperrors.Validation("Invalid post id")
 	}
 
 	return r.LikeService.LikePost(ctx, postOID, user.Username)
 }
 
+func (r *mutationResolver) UnlikePost(ctx context.Context, postID string) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+
+	return r.LikeService.UnlikePost(ctx, postOID, user.Username)
+}
+
+func (r *mutationResolver) ReactToPost(ctx context.Context, postID string, reaction *models.Reaction) (*models.Post, error) {
+	user, _ := tools.ForUserContext(ctx)
+	postOID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+
+	return r.LikeService.ReactToPost(ctx, postOID, user.Username, reaction)
+}
+
+func (r *mutationResolver) ReactToComment(ctx context.Context, commentID string, reaction *models.Reaction) (*models.Comment, error) {
+	user, _ := tools.ForUserContext(ctx)
+	commentOID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid comment id")
+	}
+
+	return r.LikeService.ReactToComment(ctx, commentOID, user.Username, reaction)
+}
+
+func (r *mutationResolver) FollowUser(ctx context.Context, username string) (*models.User, error) {
+	user, _ := tools.ForUserContext(ctx)
+	return r.FollowService.Follow(ctx, user.Username, username)
+}
+
+func (r *mutationResolver) UnfollowUser(ctx context.Context, username string) (*models.User, error) {
+	user, _ := tools.ForUserContext(ctx)
+	return r.FollowService.Unfollow(ctx, user.Username, username)
+}
+
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	user, _ := tools.ForUserContext(ctx)
+	oids := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, apperrors.Validation("Invalid notification id")
+		}
+		oids[i] = oid
+	}
+	return r.NotificationService.MarkRead(ctx, user.Username, oids)
+}
+
+func (r *mutationResolver) SetUserRole(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	return r.UserService.SetRole(ctx, username, role)
+}
+
+func (r *mutationResolver) UnlockUser(ctx context.Context, username string) (*models.User, error) {
+	return r.UserService.Unlock(ctx, username)
+}
+
+func (r *notificationResolver) ID(ctx context.Context, obj *models.Notification) (string, error) {
+	return obj.ID.Hex(), nil
+}
+
+func (r *notificationResolver) PostID(ctx context.Context, obj *models.Notification) (*string, error) {
+	if obj.PostID == nil {
+		return nil, nil
+	}
+	id := obj.PostID.Hex()
+	return &id, nil
+}
+
+func (r *notificationResolver) CommentID(ctx context.Context, obj *models.Notification) (*string, error) {
+	if obj.CommentID == nil {
+		return nil, nil
+	}
+	id := obj.CommentID.Hex()
+	return &id, nil
+}
+
 func (r *postResolver) ID(ctx context.Context, obj *models.Post) (string, error) {
 	return obj.ID.Hex(), nil
 }
 
+func (r *postResolver) Author(ctx context.Context, obj *models.Post) (*models.User, error) {
+	return loadUser(ctx, obj.Username)
+}
+
+func (r *postResolver) Comments(ctx context.Context, obj *models.Post, first *int, after *string) (*models.CommentConnection, error) {
+	page, err := internal.NewPage(first, after, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.CommentService.GetComments(ctx, obj.ID, page)
+}
+
 func (r *postResolver) LikeCount(ctx context.Context, obj *models.Post) (int, error) {
 	return len(obj.Likes), nil
 }
 
+func (r *postResolver) ReactionCounts(ctx context.Context, obj *models.Post) ([]*models.ReactionCount, error) {
+	return models.CountReactions(obj.Likes), nil
+}
+
 func (r *postResolver) CommentCount(ctx context.Context, obj *models.Post) (int, error) {
-	return len(obj.Comments), nil
+	return r.CommentService.CountComments(ctx, obj.ID)
+}
+
+func (r *postResolver) Images(ctx context.Context, obj *models.Post) ([]*models.Image, error) {
+	return r.MediaService.GetImages(ctx, obj.ImageIDs)
 }
 
-func (r *queryResolver) GetPosts(ctx context.Context) ([]*models.Post, error) {
-	posts, err := r.PostService.GetPosts(ctx)
+func (r *queryResolver) GetPosts(ctx context.Context, first *int, after *string, last *int, before *string) (*models.PostConnection, error) {
+	page, err := internal.NewPage(first, after, last, before)
 	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+	return r.PostService.GetPosts(ctx, page)
 }
 
 func (r *queryResolver) GetPost(ctx context.Context, id string) (*models.Post, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid id")
+		return nil, apperrors.Validation("Invalid id")
 	}
 	return r.PostService.GetPost(ctx, oid)
 }
@@ -158,16 +387,178 @@ func (r *queryResolver) GetUsers(ctx context.Context) ([]*models.User, error) {
 	return r.UserService.GetUsers(ctx)
 }
 
+func (r *queryResolver) HomeFeed(ctx context.Context, first *int, after *string, last *int, before *string) (*models.PostConnection, error) {
+	user, _ := tools.ForUserContext(ctx)
+	page, err := internal.NewPage(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+	return r.FollowService.HomeFeed(ctx, user.Username, page)
+}
+
+func (r *queryResolver) SearchPosts(ctx context.Context, query string, first *int, after *string) (*models.PostSearchConnection, error) {
+	return r.SearchService.SearchPosts(ctx, query, first, after)
+}
+
+func (r *queryResolver) SearchUsers(ctx context.Context, query string) ([]*models.User, error) {
+	return r.SearchService.SearchUsers(ctx, query)
+}
+
+func (r *queryResolver) PostsByTag(ctx context.Context, tag string, first *int, after *string, last *int, before *string) (*models.PostConnection, error) {
+	page, err := internal.NewPage(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+	return r.PostService.GetPostsByTag(ctx, tag, page)
+}
+
+func (r *queryResolver) TrendingTags(ctx context.Context, window *int) ([]*models.TrendingTag, error) {
+	hours := 24
+	if window != nil {
+		hours = *window
+	}
+	return r.PostService.GetTrendingTags(ctx, time.Duration(hours)*time.Hour)
+}
+
+func (r *queryResolver) Notifications(ctx context.Context, first *int, after *string, unreadOnly *bool) (*models.NotificationConnection, error) {
+	user, _ := tools.ForUserContext(ctx)
+	page, err := internal.NewPage(first, after, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.NotificationService.GetNotifications(ctx, user.Username, unreadOnly != nil && *unreadOnly, page)
+}
+
 func (r *subscriptionResolver) NewPost(ctx context.Context) (<-chan *models.Post, error) {
-	id := uuid.NewV4().String()
+	sub := r.Broker.Subscribe(ctx, pubsub.TopicNewPost)
 	events := make(chan *models.Post, 1)
+	go func() {
+		defer close(events)
+		for msg := range sub.Events() {
+			post := &models.Post{}
+			if err := msg.Decode(post); err != nil {
+				continue
+			}
+			select {
+			case events <- post:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
 
+func (r *subscriptionResolver) PostUpdated(ctx context.Context, postID string) (<-chan *models.Post, error) {
+	oid, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+	sub := r.Broker.Subscribe(ctx, pubsub.PostTopic(pubsub.TopicPostUpdated, oid.Hex()))
+	events := make(chan *models.Post, 1)
+	go func() {
+		defer close(events)
+		for msg := range sub.Events() {
+			post := &models.Post{}
+			if err := msg.Decode(post); err != nil {
+				continue
+			}
+			select {
+			case events <- post:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *models.Comment, error) {
+	oid, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+	sub := r.Broker.Subscribe(ctx, pubsub.PostTopic(pubsub.TopicCommentAdded, oid.Hex()))
+	events := make(chan *models.Comment, 1)
+	go func() {
+		defer close(events)
+		for msg := range sub.Events() {
+			comment := &models.Comment{}
+			if err := msg.Decode(comment); err != nil {
+				continue
+			}
+			select {
+			case events <- comment:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *subscriptionResolver) LikeChanged(ctx context.Context, postID string) (<-chan *models.LikeEvent, error) {
+	oid, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, apperrors.Validation("Invalid post id")
+	}
+	sub := r.Broker.Subscribe(ctx, pubsub.PostTopic(pubsub.TopicLikeChanged, oid.Hex()))
+	events := make(chan *models.LikeEvent, 1)
 	go func() {
-		<-ctx.Done()
-		delete(common.AddChannelObserver, id)
+		defer close(events)
+		for msg := range sub.Events() {
+			like := &models.LikeEvent{}
+			if err := msg.Decode(like); err != nil {
+				continue
+			}
+			select {
+			case events <- like:
+			case <-ctx.Done():
+				return
+			}
+		}
 	}()
+	return events, nil
+}
 
-	common.AddChannelObserver[id] = events
+func (r *subscriptionResolver) PostDeleted(ctx context.Context) (<-chan string, error) {
+	sub := r.Broker.Subscribe(ctx, pubsub.TopicPostDeleted)
+	events := make(chan string, 1)
+	go func() {
+		defer close(events)
+		for msg := range sub.Events() {
+			var id string
+			if err := msg.Decode(&id); err != nil {
+				continue
+			}
+			select {
+			case events <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *subscriptionResolver) NotificationReceived(ctx context.Context) (<-chan *models.Notification, error) {
+	user, _ := tools.ForUserContext(ctx)
+	sub := r.Broker.Subscribe(ctx, pubsub.UserTopic(pubsub.TopicNotification, user.Username))
+	events := make(chan *models.Notification, 1)
+	go func() {
+		defer close(events)
+		for msg := range sub.Events() {
+			notification := &models.Notification{}
+			if err := msg.Decode(notification); err != nil {
+				continue
+			}
+			select {
+			case events <- notification:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 	return events, nil
 }
 
@@ -175,15 +566,51 @@ func (r *userResolver) ID(ctx context.Context, obj *models.User) (string, error)
 	return obj.ID.Hex(), nil
 }
 
+func (r *userResolver) Followers(ctx context.Context, obj *models.User, first *int, after *string, last *int, before *string) (*models.UserConnection, error) {
+	page, err := internal.NewPage(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+	return r.FollowService.GetFollowers(ctx, obj.Username, page)
+}
+
+func (r *userResolver) Following(ctx context.Context, obj *models.User, first *int, after *string, last *int, before *string) (*models.UserConnection, error) {
+	page, err := internal.NewPage(first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+	return r.FollowService.GetFollowing(ctx, obj.Username, page)
+}
+
+func (r *userResolver) FollowerCount(ctx context.Context, obj *models.User) (int, error) {
+	return r.FollowService.CountFollowers(ctx, obj.Username)
+}
+
+func (r *userResolver) FollowingCount(ctx context.Context, obj *models.User) (int, error) {
+	return r.FollowService.CountFollowing(ctx, obj.Username)
+}
+
+// Attachment returns generated.AttachmentResolver implementation.
+func (r *Resolver) Attachment() generated.AttachmentResolver { return &attachmentResolver{r} }
+
 // Comment returns generated.CommentResolver implementation.
 func (r *Resolver) Comment() generated.CommentResolver { return &commentResolver{r} }
 
+// Image returns generated.ImageResolver implementation.
+func (r *Resolver) Image() generated.ImageResolver { return &imageResolver{r} }
+
+// ImageVariant returns generated.ImageVariantResolver implementation.
+func (r *Resolver) ImageVariant() generated.ImageVariantResolver { return &imageVariantResolver{r} }
+
 // Like returns generated.LikeResolver implementation.
 func (r *Resolver) Like() generated.LikeResolver { return &likeResolver{r} }
 
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Notification returns generated.NotificationResolver implementation.
+func (r *Resolver) Notification() generated.NotificationResolver { return &notificationResolver{r} }
+
 // Post returns generated.PostResolver implementation.
 func (r *Resolver) Post() generated.PostResolver { return &postResolver{r} }
 
@@ -196,9 +623,13 @@ func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subsc
 // User returns generated.UserResolver implementation.
 func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
+type attachmentResolver struct{ *Resolver }
 type commentResolver struct{ *Resolver }
+type imageResolver struct{ *Resolver }
+type imageVariantResolver struct{ *Resolver }
 type likeResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
@@ -8,18 +8,20 @@ import (
 )
 
 type Claims struct {
-	User interface{} `json:"user"`
+	User      interface{} `json:"user"`
+	SessionID string      `json:"sid"`
 	jwt.StandardClaims
 }
 
-func CreateTokenWithUser(jwtKey string, user interface{}, expirationMinute int) (string, error) {
+func CreateTokenWithUser(jwtKey string, user interface{}, sessionID string, expirationMinute int) (string, error) {
 	// Declare the expiration time of the token
 	// here, we have kept it as 24 hours
 	expirationTime := time.Now().Add(time.Duration(expirationMinute) * time.Minute)
 
 	// Create the JWT claims, which includes the username and expiry time
 	claims := &Claims{
-		User: &user,
+		User:      &user,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
 			// In JWT, the expiry time is expressed as unix milliseconds
 			ExpiresAt: expirationTime.Unix(),
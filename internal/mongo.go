@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const duplicateKeyCode = 11000
+
+// IsDuplicateKeyError reports whether err was caused by a unique index. A
+// write exception may hold no write error at all, e.g. on a write concern
+// error, so every error it holds is checked.
+func IsDuplicateKeyError(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, writeErr := range e.WriteErrors {
+			if writeErr.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	case mongo.BulkWriteException:
+		for _, writeErr := range e.WriteErrors {
+			if writeErr.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == duplicateKeyCode
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 50
+
+	cursorPrefix = "cursor:"
+	offsetPrefix = "offset:"
+)
+
+// Page is a keyset window over a collection ordered by _id, newest first
+// unless Ascending is set. ObjectIDs embed their creation time so the order
+// follows createdAt.
+type Page struct {
+	Limit     int
+	Cursor    *primitive.ObjectID
+	Backward  bool
+	Ascending bool
+}
+
+// NewPage builds a page from Relay connection arguments.
+func NewPage(first *int, after *string, last *int, before *string) (*Page, error) {
+	if first != nil && last != nil {
+		return nil, apperrors.Validation("Cannot use first and last together")
+	}
+	if after != nil && before != nil {
+		return nil, apperrors.Validation("Cannot use after and before together")
+	}
+
+	page := &Page{Limit: DefaultPageSize}
+	size := first
+	cursor := after
+	if last != nil || before != nil {
+		page.Backward = true
+		size = last
+		cursor = before
+	}
+
+	if size != nil {
+		if *size < 0 {
+			return nil, apperrors.Validation("Page size must not be negative")
+		}
+		page.Limit = *size
+		if page.Limit > MaxPageSize {
+			page.Limit = MaxPageSize
+		}
+	}
+
+	if cursor != nil {
+		oid, err := DecodeCursor(*cursor)
+		if err != nil {
+			return nil, err
+		}
+		page.Cursor = &oid
+	}
+	return page, nil
+}
+
+// EncodeCursor returns an opaque cursor for the document id.
+func EncodeCursor(id primitive.ObjectID) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + id.Hex()))
+}
+
+// DecodeCursor returns the document id of a cursor made by EncodeCursor.
+func DecodeCursor(cursor string) (primitive.ObjectID, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return primitive.NilObjectID, apperrors.Validation("Invalid cursor")
+	}
+	oid, err := primitive.ObjectIDFromHex(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil {
+		return primitive.NilObjectID, apperrors.Validation("Invalid cursor")
+	}
+	return oid, nil
+}
+
+// EncodeOffsetCursor returns an opaque cursor for a position in a ranked list,
+// used where the order is not a keyset like search relevance.
+func EncodeOffsetCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(offsetPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeOffsetCursor returns the position of a cursor made by EncodeOffsetCursor.
+func DecodeOffsetCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), offsetPrefix) {
+		return 0, apperrors.Validation("Invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), offsetPrefix))
+	if err != nil || offset < 0 {
+		return 0, apperrors.Validation("Invalid cursor")
+	}
+	return offset, nil
+}
+
+// Filter adds the keyset condition of the page to filter.
+func (p *Page) Filter(filter bson.M) bson.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if p.Cursor == nil {
+		return filter
+	}
+	op := "$gt"
+	if p.Backward == p.Ascending {
+		op = "$lt"
+	}
+	filter["_id"] = bson.M{op: *p.Cursor}
+	return filter
+}
+
+// FindOptions sorts in the page direction and fetches one extra document
+// so Size can tell whether another page exists.
+func (p *Page) FindOptions() *options.FindOptions {
+	order := 1
+	if p.Backward == p.Ascending {
+		order = -1
+	}
+	return options.Find().
+		SetSort(bson.D{{Key: "_id", Value: order}}).
+		SetLimit(int64(p.Limit + 1))
+}
+
+// Size returns how many of the fetched documents belong to the page and
+// whether there are more documents beyond it.
+func (p *Page) Size(fetched int) (int, bool) {
+	if fetched > p.Limit {
+		return p.Limit, true
+	}
+	return fetched, false
+}
+
+// PageInfo builds the connection page info from the edge cursors.
+func (p *Page) PageInfo(hasMore bool, cursors []string) *models.PageInfo {
+	info := &models.PageInfo{}
+	if p.Backward {
+		info.HasPreviousPage = hasMore
+		info.HasNextPage = p.Cursor != nil
+	} else {
+		info.HasNextPage = hasMore
+		info.HasPreviousPage = p.Cursor != nil
+	}
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+	return info
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NewRedisPool returns the pool of connections to the Redis at url shared by
+// the broker, the persisted query cache and the rate limiter. It fails fast
+// when Redis can't be reached at startup.
+func NewRedisPool(url string) (*redis.Pool, error) {
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 5 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(url)
+		},
+	}
+
+	conn := pool.Get()
+	_, err := conn.Do("PING")
+	conn.Close()
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
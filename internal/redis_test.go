@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestNewRedisPool(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	pool, err := NewRedisPool("redis://" + m.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := redis.String(conn.Do("GET", "key")); err != nil || value != "value" {
+		t.Errorf("got %q, %v, want value", value, err)
+	}
+}
+
+func TestNewRedisPoolUnreachable(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := m.Addr()
+	m.Close()
+
+	if _, err := NewRedisPool("redis://" + addr); err == nil {
+		t.Fatal("connected to a stopped Redis")
+	}
+}
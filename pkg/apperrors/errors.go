@@ -0,0 +1,104 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Code tells clients what kind of error happened, it is sent in the
+// extensions of GraphQL errors.
+type Code string
+
+const (
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeValidation      Code = "BAD_USER_INPUT"
+	CodeConflict        Code = "CONFLICT"
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeInternal        Code = "INTERNAL_SERVER_ERROR"
+)
+
+// Error is an error whose message can be shown to clients. Err is the cause,
+// it is only logged. Extensions are sent to clients along with the code.
+type Error struct {
+	Code       Code
+	Message    string
+	Err        error
+	Extensions map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any error of the same code, so errors.Is(err, ErrNotFound) holds
+// for every not found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels to test the code of an error with errors.Is.
+var (
+	ErrNotFound        = &Error{Code: CodeNotFound, Message: "Not found"}
+	ErrUnauthenticated = &Error{Code: CodeUnauthenticated, Message: "Unauthorize"}
+	ErrForbidden       = &Error{Code: CodeForbidden, Message: "Action not allowed"}
+	ErrValidation      = &Error{Code: CodeValidation, Message: "Invalid input"}
+	ErrConflict        = &Error{Code: CodeConflict, Message: "Conflict"}
+	ErrRateLimited     = &Error{Code: CodeRateLimited, Message: "Too many requests"}
+)
+
+func New(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return New(CodeNotFound, format, args...)
+}
+
+func Unauthenticated(format string, args ...interface{}) error {
+	return New(CodeUnauthenticated, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return New(CodeForbidden, format, args...)
+}
+
+func Validation(format string, args ...interface{}) error {
+	return New(CodeValidation, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return New(CodeConflict, format, args...)
+}
+
+// RateLimited tells the client to retry after the given delay, sent in
+// seconds in the retryAfter extension.
+func RateLimited(retryAfter time.Duration) error {
+	return &Error{
+		Code:       CodeRateLimited,
+		Message:    "Too many requests",
+		Extensions: map[string]interface{}{"retryAfter": int(math.Ceil(retryAfter.Seconds()))},
+	}
+}
+
+// Wrap gives a cause a message and a code, the cause is kept for logging.
+func Wrap(err error, code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// CodeOf returns the code of err, CodeInternal when it is not an *Error.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,79 @@
+package comment
+
+import (
+	"context"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// embeddedComments is the shape of posts created before comments got their own collection.
+type embeddedComments struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Comments []models.Comment   `bson:"comments"`
+}
+
+// MigrateEmbeddedComments moves comments embedded in post documents into the
+// comments collection. Comments keep their ids so running it again after an
+// interruption does not duplicate them.
+func MigrateEmbeddedComments(ctx context.Context, db *mongo.Database, log *logger.AppLog) (int, error) {
+	posts := db.Collection("posts")
+	comments := db.Collection(collectionName)
+
+	filter := bson.M{"comments": bson.M{"$exists": true}}
+	cursor, err := posts.Find(ctx, filter, options.Find().SetProjection(bson.M{"comments": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		post := &embeddedComments{}
+		if err := cursor.Decode(post); err != nil {
+			return migrated, err
+		}
+
+		if len(post.Comments) > 0 {
+			docs := make([]interface{}, len(post.Comments))
+			for i, c := range post.Comments {
+				c.PostID = post.ID
+				docs[i] = c
+			}
+			_, err := comments.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+			if err != nil && !onlyDuplicateKeyErrors(err) {
+				return migrated, err
+			}
+			migrated += len(post.Comments)
+		}
+
+		if _, err := posts.UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$unset": bson.M{"comments": ""}}); err != nil {
+			return migrated, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, err
+	}
+
+	if migrated > 0 {
+		log.Infof("Migrated %d embedded comments", migrated)
+	}
+	return migrated, nil
+}
+
+func onlyDuplicateKeyErrors(err error) bool {
+	bulkErr, ok := err.(mongo.BulkWriteException)
+	if !ok || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, e := range bulkErr.WriteErrors {
+		if e.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
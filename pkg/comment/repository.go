@@ -2,29 +2,307 @@ package comment
 
 import (
 	"context"
+	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
-	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	collectionName = "comments"
+)
+
+var (
+	commentRepo *repository
 )
 
 type CommentRepository interface {
-	Create(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error)
-	DeleteByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error)
+	Create(ctx context.Context, comment *models.Comment) (*models.Comment, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	GetListByPostID(ctx context.Context, postID primitive.ObjectID, page *internal.Page) ([]*models.Comment, bool, error)
+	GetReplies(ctx context.Context, parentID primitive.ObjectID) ([]*models.Comment, error)
+	Search(ctx context.Context, query string, limit int) ([]*models.Comment, []float64, error)
+	CountByPostID(ctx context.Context, postID primitive.ObjectID) (int, error)
+	CountReplies(ctx context.Context, parentID primitive.ObjectID) (int, error)
+	DeleteByID(ctx context.Context, id primitive.ObjectID) error
+	DeleteByPostID(ctx context.Context, postID primitive.ObjectID) (int, error)
+	MarkDeleted(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	UpdateBody(ctx context.Context, id primitive.ObjectID, oldBody string, body string, revision models.Revision) (*models.Comment, error)
+	SetReaction(ctx context.Context, id primitive.ObjectID, username string, reaction models.Reaction) (*models.Comment, bool, error)
+	RemoveReaction(ctx context.Context, id primitive.ObjectID, username string) (*models.Comment, error)
 }
 
 type repository struct {
-	Logger *logger.AppLog
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewCommentRepository(db *mongo.Database, log *logger.AppLog) CommentRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "postId", Value: 1},
+				{Key: "createdAt", Value: 1},
+			},
+		},
+		{
+			// top level comments of a post are paginated by _id
+			Keys: bson.D{
+				{Key: "postId", Value: 1},
+				{Key: "parentId", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "parentId", Value: 1},
+				{Key: "createdAt", Value: 1},
+			},
+		},
+		{
+			// full text search over comment bodies
+			Keys: bson.D{{Key: "body", Value: "text"}},
+		},
+	}
+
+	ctx := context.Background()
+	commentCollection := db.Collection(collectionName)
+	commentCollection.Indexes().CreateMany(ctx, mod)
+	commentRepo = &repository{
+		Collection: commentCollection,
+		Logger:     log,
+	}
+	return commentRepo
+}
+
+func GetCommentRepository() CommentRepository {
+	return commentRepo
+}
+
+func (r *repository) Create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	result, err := r.Collection.InsertOne(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+	return comment, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	comment := &models.Comment{}
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(comment); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found comment")
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (r *repository) GetListByPostID(ctx context.Context, postID primitive.ObjectID, page *internal.Page) ([]*models.Comment, bool, error) {
+	comments := []*models.Comment{}
+	filter := page.Filter(bson.M{"postId": postID, "parentId": nil})
+
+	cursor, err := r.Collection.Find(ctx, filter, page.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, false, err
+	}
+
+	size, hasMore := page.Size(len(comments))
+	comments = comments[:size]
+	if page.Backward {
+		for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+			comments[i], comments[j] = comments[j], comments[i]
+		}
+	}
+	return comments, hasMore, nil
+}
+
+func (r *repository) GetReplies(ctx context.Context, parentID primitive.ObjectID) ([]*models.Comment, error) {
+	comments := []*models.Comment{}
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"parentId": parentID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Search returns the comments matching the text query, most relevant first,
+// together with their text scores. Deleted comments are left out.
+func (r *repository) Search(ctx context.Context, query string, limit int) ([]*models.Comment, []float64, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	filter := bson.M{"$text": bson.M{"$search": query}, "deleted": bson.M{"$ne": true}}
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []struct {
+		models.Comment `bson:",inline"`
+		Score          float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, nil, err
+	}
+
+	comments := make([]*models.Comment, len(hits))
+	scores := make([]float64, len(hits))
+	for i := range hits {
+		comments[i] = &hits[i].Comment
+		scores[i] = hits[i].Score
+	}
+	return comments, scores, nil
+}
+
+func (r *repository) CountByPostID(ctx context.Context, postID primitive.ObjectID) (int, error) {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"postId": postID, "deleted": bson.M{"$ne": true}})
+	return int(count), err
 }
 
-func NewCommentRepository(log *logger.AppLog) CommentRepository {
-	return &repository{Logger: log}
+func (r *repository) CountReplies(ctx context.Context, parentID primitive.ObjectID) (int, error) {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"parentId": parentID})
+	return int(count), err
 }
 
-func (r *repository) Create(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error) {
-	return post.GetPostRepository().CreateComment(ctx, postID, comment)
+func (r *repository) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *repository) DeleteByPostID(ctx context.Context, postID primitive.ObjectID) (int, error) {
+	result, err := r.Collection.DeleteMany(ctx, bson.M{"postId": postID})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// MarkDeleted keeps the comment for its replies but removes everything of its
+// author: the text with its revisions, the author and the reactions.
+func (r *repository) MarkDeleted(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	comment := &models.Comment{}
+	update := bson.M{
+		"$set": bson.M{
+			"body":    DeletedPlaceholder,
+			"deleted": true,
+		},
+		"$unset": bson.M{
+			"username":  "",
+			"revisions": "",
+			"editedAt":  "",
+			"likes":     "",
+		},
+	}
+	err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(comment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found comment")
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+// UpdateBody replaces the body only if it still is oldBody, so concurrent edits
+// cannot lose a revision.
+func (r *repository) UpdateBody(ctx context.Context, id primitive.ObjectID, oldBody string, body string, revision models.Revision) (*models.Comment, error) {
+	filter := bson.M{"_id": id, "body": oldBody}
+	comment := &models.Comment{}
+	update := bson.M{
+		"$set": bson.M{
+			"body":     body,
+			"editedAt": time.Now().Format(time.RFC3339),
+		},
+		"$push": bson.M{
+			"revisions": bson.M{
+				"$each":  []models.Revision{revision},
+				"$slice": -models.MaxRevisions,
+			},
+		},
+	}
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(comment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.Conflict("Comment was changed, please try again")
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+// SetReaction replaces the reaction of the user to a comment or adds it, and
+// reports whether it was added. Deleted comments cannot get reactions.
+func (r *repository) SetReaction(ctx context.Context, id primitive.ObjectID, username string, reaction models.Reaction) (*models.Comment, bool, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	for attempt := 0; attempt < 2; attempt++ {
+		comment := &models.Comment{}
+		filter := bson.M{"_id": id, "deleted": bson.M{"$ne": true}, "likes.username": username}
+		update := bson.M{"$set": bson.M{"likes.$.reaction": reaction}}
+		err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(comment)
+		if err == nil {
+			return comment, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+
+		filter = bson.M{"_id": id, "deleted": bson.M{"$ne": true}, "likes.username": bson.M{"$ne": username}}
+		update = bson.M{"$push": bson.M{"likes": models.Like{
+			ID:        primitive.NewObjectID(),
+			Username:  username,
+			CreatedAt: time.Now().Format(time.RFC3339),
+			Reaction:  reaction,
+		}}}
+		err = r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(comment)
+		if err == nil {
+			return comment, true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+		// Either the comment is gone or the user reacted in between
+		existing, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing.Deleted {
+			return nil, false, apperrors.Validation("Cannot react to a deleted comment")
+		}
+	}
+	return nil, false, apperrors.Conflict("Comment was changed, please try again")
 }
 
-func (r *repository) DeleteByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error) {
-	return post.GetPostRepository().DeleteCommentByID(ctx, postID, commentID)
+func (r *repository) RemoveReaction(ctx context.Context, id primitive.ObjectID, username string) (*models.Comment, error) {
+	comment := &models.Comment{}
+	update := bson.M{"$pull": bson.M{"likes": bson.M{"username": username}}}
+	err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(comment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found comment")
+		}
+		return nil, err
+	}
+	return comment, nil
 }
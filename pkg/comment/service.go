@@ -2,46 +2,201 @@ package comment
 
 import (
 	"context"
-	"fmt"
+	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
 	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+)
+
+const (
+	// MaxDepth is the deepest level a reply can be nested at, top level comments are at depth 0.
+	MaxDepth = 5
+	// DeletedPlaceholder replaces the body of a deleted comment that still has replies.
+	DeletedPlaceholder = "[deleted]"
 )
 
 type CommentService interface {
-	CreateComment(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error)
-	DeleteComment(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID, username string) (*models.Post, error)
+	CreateComment(ctx context.Context, postID primitive.ObjectID, parentID *primitive.ObjectID, comment *models.Comment) (*models.Post, error)
+	DeleteComment(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID, user *models.User) (*models.Post, error)
+	GetComments(ctx context.Context, postID primitive.ObjectID, page *internal.Page) (*models.CommentConnection, error)
+	GetReplies(ctx context.Context, commentID primitive.ObjectID) ([]*models.Comment, error)
+	CountComments(ctx context.Context, postID primitive.ObjectID) (int, error)
+	EditComment(ctx context.Context, id primitive.ObjectID, username string, body string) (*models.Comment, error)
 }
 
 type service struct {
-	repository CommentRepository
-	Logger     *logger.AppLog
+	repository    CommentRepository
+	broker        pubsub.Broker
+	notifications notification.NotificationService
+	Logger        *logger.AppLog
 }
 
-func NewCommentService(log *logger.AppLog) CommentService {
+func NewCommentService(db *mongo.Database, log *logger.AppLog, broker pubsub.Broker, notifications notification.NotificationService) CommentService {
 	return &service{
-		repository: NewCommentRepository(log),
-		Logger:     log,
+		repository:    NewCommentRepository(db, log),
+		broker:        broker,
+		notifications: notifications,
+		Logger:        log,
 	}
 }
 
-func (s *service) CreateComment(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error) {
-	return s.repository.Create(ctx, postID, comment)
+func (s *service) CreateComment(ctx context.Context, postID primitive.ObjectID, parentID *primitive.ObjectID, comment *models.Comment) (*models.Post, error) {
+	p, err := post.GetPostRepository().GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *models.Comment
+	if parentID != nil {
+		parent, err = s.repository.GetByID(ctx, *parentID)
+		if err != nil || parent.PostID != postID {
+			return nil, apperrors.NotFound("Not found comment")
+		}
+		if parent.Deleted {
+			return nil, apperrors.Validation("Cannot reply to a deleted comment")
+		}
+		if parent.Depth >= MaxDepth {
+			return nil, apperrors.Validation("Maximum reply depth reached")
+		}
+		comment.ParentID = parentID
+		comment.Depth = parent.Depth + 1
+	}
+
+	comment.PostID = postID
+	if _, err := s.repository.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	s.broker.Publish(pubsub.PostTopic(pubsub.TopicCommentAdded, postID.Hex()), comment)
+	s.broker.Publish(pubsub.PostTopic(pubsub.TopicPostUpdated, postID.Hex()), p)
+
+	// The author of a replied comment hears about the reply, the author of the
+	// post about any other comment.
+	if parent != nil {
+		s.notifications.Notify(ctx, &models.Notification{
+			Recipient: parent.Username,
+			Actor:     comment.Username,
+			Type:      models.NotificationReply,
+			PostID:    &postID,
+			CommentID: &comment.ID,
+		})
+	}
+	if parent == nil || parent.Username != p.Username {
+		s.notifications.Notify(ctx, &models.Notification{
+			Recipient: p.Username,
+			Actor:     comment.Username,
+			Type:      models.NotificationComment,
+			PostID:    &postID,
+			CommentID: &comment.ID,
+		})
+	}
+	return p, nil
 }
 
-func (s *service) DeleteComment(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID, username string) (*models.Post, error) {
-	p, err := post.GetPostRepository().GetCommentByID(ctx, postID, commentID)
+// DeleteComment deletes a comment of the user or on a post of the user,
+// moderators can delete any comment.
+func (s *service) DeleteComment(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID, user *models.User) (*models.Post, error) {
+	p, err := post.GetPostRepository().GetByID(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
-	if p.Username == username {
-		return s.repository.DeleteByID(ctx, postID, commentID)
+	target, err := s.repository.GetByID(ctx, commentID)
+	if err != nil || target.PostID != postID {
+		return nil, apperrors.NotFound("Not found comment")
+	}
+	if p.Username != user.Username && target.Username != user.Username && !user.HasRole(models.RoleModerator) {
+		return nil, apperrors.Forbidden("Action not allowed")
 	}
-	for _, v := range p.Comments {
-		if v.ID == commentID && v.Username == username {
-			return s.repository.DeleteByID(ctx, postID, commentID)
+	if target.Deleted {
+		return p, nil
+	}
+
+	// Keep a placeholder so the replies stay in their thread
+	replies, err := s.repository.CountReplies(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if replies > 0 {
+		if _, err := s.repository.MarkDeleted(ctx, commentID); err != nil {
+			return nil, err
 		}
+		s.broker.Publish(pubsub.PostTopic(pubsub.TopicPostUpdated, postID.Hex()), p)
+		return p, nil
+	}
+
+	if err := s.repository.DeleteByID(ctx, commentID); err != nil {
+		return nil, err
+	}
+
+	// Removing the last reply of a deleted comment leaves an empty
+	// placeholder, remove those up the thread as well.
+	for parentID := target.ParentID; parentID != nil; {
+		parent, err := s.repository.GetByID(ctx, *parentID)
+		if err != nil || !parent.Deleted {
+			break
+		}
+		if replies, err := s.repository.CountReplies(ctx, parent.ID); err != nil || replies > 0 {
+			break
+		}
+		if err := s.repository.DeleteByID(ctx, parent.ID); err != nil {
+			return nil, err
+		}
+		parentID = parent.ParentID
+	}
+	s.broker.Publish(pubsub.PostTopic(pubsub.TopicPostUpdated, postID.Hex()), p)
+	return p, nil
+}
+
+func (s *service) GetComments(ctx context.Context, postID primitive.ObjectID, page *internal.Page) (*models.CommentConnection, error) {
+	page.Ascending = true
+	comments, hasMore, err := s.repository.GetListByPostID(ctx, postID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*models.CommentEdge, len(comments))
+	cursors := make([]string, len(comments))
+	for i, c := range comments {
+		cursors[i] = internal.EncodeCursor(c.ID)
+		edges[i] = &models.CommentEdge{Cursor: cursors[i], Node: c}
+	}
+	return &models.CommentConnection{
+		Edges:    edges,
+		PageInfo: page.PageInfo(hasMore, cursors),
+	}, nil
+}
+
+func (s *service) GetReplies(ctx context.Context, commentID primitive.ObjectID) ([]*models.Comment, error) {
+	return s.repository.GetReplies(ctx, commentID)
+}
+
+func (s *service) CountComments(ctx context.Context, postID primitive.ObjectID) (int, error) {
+	return s.repository.CountByPostID(ctx, postID)
+}
+
+func (s *service) EditComment(ctx context.Context, id primitive.ObjectID, username string, body string) (*models.Comment, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, apperrors.Validation("Body must not be empty")
+	}
+	comment, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Username != username || comment.Deleted {
+		return nil, apperrors.Forbidden("Action not allowed")
+	}
+	if comment.Body == body {
+		return comment, nil
+	}
+
+	revision := models.Revision{Body: comment.Body, CreatedAt: comment.CreatedAt}
+	if comment.EditedAt != nil {
+		revision.CreatedAt = *comment.EditedAt
 	}
-	return nil, fmt.Errorf("Action not allowed")
+	return s.repository.UpdateBody(ctx, id, comment.Body, body, revision)
 }
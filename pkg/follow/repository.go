@@ -0,0 +1,165 @@
+package follow
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "follows"
+)
+
+var (
+	followRepo *repository
+)
+
+type FollowRepository interface {
+	Create(ctx context.Context, follower, followee string) (*models.Follow, error)
+	Delete(ctx context.Context, follower, followee string) error
+	GetFollowers(ctx context.Context, username string, page *internal.Page) ([]*models.Follow, bool, error)
+	GetFollowing(ctx context.Context, username string, page *internal.Page) ([]*models.Follow, bool, error)
+	GetAllFollowing(ctx context.Context, username string) ([]string, error)
+	CountFollowers(ctx context.Context, username string) (int, error)
+	CountFollowing(ctx context.Context, username string) (int, error)
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewFollowRepository(db *mongo.Database, log *logger.AppLog) FollowRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "follower", Value: 1},
+				{Key: "followee", Value: 1},
+			},
+			// a user follows another user at most once
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// pages of followers, the latest follow first
+			Keys: bson.D{
+				{Key: "followee", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			// pages of followed users, the latest follow first
+			Keys: bson.D{
+				{Key: "follower", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	followCollection := db.Collection(collectionName)
+	followCollection.Indexes().CreateMany(ctx, mod)
+	followRepo = &repository{
+		Collection: followCollection,
+		Logger:     log,
+	}
+	return followRepo
+}
+
+func GetFollowRepository() FollowRepository {
+	return followRepo
+}
+
+func (r *repository) Create(ctx context.Context, follower, followee string) (*models.Follow, error) {
+	follow := &models.Follow{
+		Follower:  follower,
+		Followee:  followee,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	result, err := r.Collection.InsertOne(ctx, follow)
+	if err != nil {
+		return nil, err
+	}
+	follow.ID = result.InsertedID.(primitive.ObjectID)
+	return follow, nil
+}
+
+func (r *repository) Delete(ctx context.Context, follower, followee string) error {
+	_, err := r.Collection.DeleteOne(ctx, bson.M{"follower": follower, "followee": followee})
+	return err
+}
+
+func (r *repository) GetFollowers(ctx context.Context, username string, page *internal.Page) ([]*models.Follow, bool, error) {
+	return r.findPage(ctx, bson.M{"followee": username}, page)
+}
+
+func (r *repository) GetFollowing(ctx context.Context, username string, page *internal.Page) ([]*models.Follow, bool, error) {
+	return r.findPage(ctx, bson.M{"follower": username}, page)
+}
+
+// GetAllFollowing returns the usernames of every user followed by the user.
+func (r *repository) GetAllFollowing(ctx context.Context, username string) ([]string, error) {
+	follows, err := r.find(ctx, bson.M{"follower": username})
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, len(follows))
+	for i, f := range follows {
+		usernames[i] = f.Followee
+	}
+	return usernames, nil
+}
+
+func (r *repository) CountFollowers(ctx context.Context, username string) (int, error) {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"followee": username})
+	return int(count), err
+}
+
+func (r *repository) CountFollowing(ctx context.Context, username string) (int, error) {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"follower": username})
+	return int(count), err
+}
+
+func (r *repository) findPage(ctx context.Context, filter bson.M, page *internal.Page) ([]*models.Follow, bool, error) {
+	follows := []*models.Follow{}
+
+	cursor, err := r.Collection.Find(ctx, page.Filter(filter), page.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, false, err
+	}
+
+	size, hasMore := page.Size(len(follows))
+	follows = follows[:size]
+	if page.Backward {
+		for i, j := 0, len(follows)-1; i < j; i, j = i+1, j-1 {
+			follows[i], follows[j] = follows[j], follows[i]
+		}
+	}
+	return follows, hasMore, nil
+}
+
+func (r *repository) find(ctx context.Context, filter bson.M) ([]*models.Follow, error) {
+	var follows []*models.Follow
+
+	cursor, err := r.Collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
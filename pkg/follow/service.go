@@ -0,0 +1,145 @@
+package follow
+
+import (
+	"context"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
+	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/user"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type FollowService interface {
+	Follow(ctx context.Context, follower, followee string) (*models.User, error)
+	Unfollow(ctx context.Context, follower, followee string) (*models.User, error)
+	GetFollowers(ctx context.Context, username string, page *internal.Page) (*models.UserConnection, error)
+	GetFollowing(ctx context.Context, username string, page *internal.Page) (*models.UserConnection, error)
+	CountFollowers(ctx context.Context, username string) (int, error)
+	CountFollowing(ctx context.Context, username string) (int, error)
+	HomeFeed(ctx context.Context, username string, page *internal.Page) (*models.PostConnection, error)
+}
+
+type service struct {
+	repository    FollowRepository
+	notifications notification.NotificationService
+	Logger        *logger.AppLog
+}
+
+func NewFollowService(db *mongo.Database, log *logger.AppLog, notifications notification.NotificationService) FollowService {
+	r := NewFollowRepository(db, log)
+	return &service{repository: r, notifications: notifications, Logger: log}
+}
+
+func (s *service) Follow(ctx context.Context, follower, followee string) (*models.User, error) {
+	if follower == followee {
+		return nil, apperrors.Validation("You cannot follow yourself")
+	}
+	u, err := user.GetUserRepository().GetByUsername(ctx, followee)
+	if err != nil {
+		return nil, apperrors.NotFound("Not found user")
+	}
+
+	if _, err := s.repository.Create(ctx, follower, followee); err != nil {
+		// Following twice is not an error, the user is already followed.
+		if internal.IsDuplicateKeyError(err) {
+			return u, nil
+		}
+		s.Logger.Errorf("Follow error %#v", err)
+		return nil, err
+	}
+	s.notifications.Notify(ctx, &models.Notification{
+		Recipient: followee,
+		Actor:     follower,
+		Type:      models.NotificationFollow,
+	})
+	return u, nil
+}
+
+func (s *service) Unfollow(ctx context.Context, follower, followee string) (*models.User, error) {
+	u, err := user.GetUserRepository().GetByUsername(ctx, followee)
+	if err != nil {
+		return nil, apperrors.NotFound("Not found user")
+	}
+	if err := s.repository.Delete(ctx, follower, followee); err != nil {
+		s.Logger.Errorf("Unfollow error %#v", err)
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *service) GetFollowers(ctx context.Context, username string, page *internal.Page) (*models.UserConnection, error) {
+	follows, hasMore, err := s.repository.GetFollowers(ctx, username, page)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, len(follows))
+	for i, f := range follows {
+		usernames[i] = f.Follower
+	}
+	return s.userConnection(ctx, page, follows, usernames, hasMore)
+}
+
+func (s *service) GetFollowing(ctx context.Context, username string, page *internal.Page) (*models.UserConnection, error) {
+	follows, hasMore, err := s.repository.GetFollowing(ctx, username, page)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make([]string, len(follows))
+	for i, f := range follows {
+		usernames[i] = f.Followee
+	}
+	return s.userConnection(ctx, page, follows, usernames, hasMore)
+}
+
+// userConnection pages the users in the order of their follows, the cursors
+// are the ids of the follows.
+func (s *service) userConnection(ctx context.Context, page *internal.Page, follows []*models.Follow, usernames []string, hasMore bool) (*models.UserConnection, error) {
+	users, err := user.GetUserRepository().GetListByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+	byUsername := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byUsername[u.Username] = u
+	}
+
+	edges := []*models.UserEdge{}
+	cursors := []string{}
+	for i, f := range follows {
+		u, ok := byUsername[usernames[i]]
+		if !ok {
+			continue
+		}
+		cursor := internal.EncodeCursor(f.ID)
+		edges = append(edges, &models.UserEdge{Cursor: cursor, Node: u})
+		cursors = append(cursors, cursor)
+	}
+	return &models.UserConnection{
+		Edges:    edges,
+		PageInfo: page.PageInfo(hasMore, cursors),
+	}, nil
+}
+
+func (s *service) CountFollowers(ctx context.Context, username string) (int, error) {
+	return s.repository.CountFollowers(ctx, username)
+}
+
+func (s *service) CountFollowing(ctx context.Context, username string) (int, error) {
+	return s.repository.CountFollowing(ctx, username)
+}
+
+func (s *service) HomeFeed(ctx context.Context, username string, page *internal.Page) (*models.PostConnection, error) {
+	usernames, err := s.repository.GetAllFollowing(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	posts, hasMore, err := post.GetPostRepository().GetListByUsernames(ctx, usernames, page)
+	if err != nil {
+		return nil, err
+	}
+	return post.NewPostConnection(page, posts, hasMore), nil
+}
@@ -3,39 +3,124 @@ package like
 import (
 	"context"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/comment"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
 	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type LikeService interface {
 	LikePost(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error)
+	UnlikePost(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error)
+	ReactToPost(ctx context.Context, postID primitive.ObjectID, username string, reaction *models.Reaction) (*models.Post, error)
+	ReactToComment(ctx context.Context, commentID primitive.ObjectID, username string, reaction *models.Reaction) (*models.Comment, error)
 }
 
 type service struct {
-	repository LikeRepository
-	Logger     *logger.AppLog
+	repository    LikeRepository
+	broker        pubsub.Broker
+	notifications notification.NotificationService
+	Logger        *logger.AppLog
 }
 
-func NewLikeService(log *logger.AppLog) LikeService {
+func NewLikeService(log *logger.AppLog, broker pubsub.Broker, notifications notification.NotificationService) LikeService {
 	return &service{
-		repository: NewLikeRepository(log),
-		Logger:     log,
+		repository:    NewLikeRepository(log),
+		broker:        broker,
+		notifications: notifications,
+		Logger:        log,
 	}
 }
 
+// LikePost likes the post unless the user already reacted to it, liking
+// twice changes nothing.
 func (s *service) LikePost(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error) {
-	postRepo := post.GetPostRepository()
-	post, err := postRepo.FindLikeByUsername(ctx, postID, username)
+	p, added, err := post.GetPostRepository().CreateLike(ctx, postID, username)
 	if err != nil {
-		if err.Error() == "Not found post" {
-			post, err = postRepo.CreateLike(ctx, postID, username)
-			if err != nil {
-				return nil, err
-			}
-			return post, nil
-		}
 		return nil, err
 	}
-	return postRepo.DeleteLikeByID(ctx, postID, username)
+	if added {
+		reaction := models.ReactionLike
+		s.notifyLikeChanged(p, username, &reaction)
+		s.notifications.Notify(ctx, &models.Notification{
+			Recipient: p.Username,
+			Actor:     username,
+			Type:      models.NotificationLike,
+			PostID:    &p.ID,
+		})
+	}
+	return p, nil
+}
+
+// UnlikePost takes back the reaction of the user to the post, whichever it
+// is. Unliking a post that is not liked changes nothing.
+func (s *service) UnlikePost(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error) {
+	p, removed, err := post.GetPostRepository().DeleteLikeByID(ctx, postID, username)
+	if err != nil {
+		return nil, err
+	}
+	if removed {
+		s.notifyLikeChanged(p, username, nil)
+	}
+	return p, nil
+}
+
+// ReactToPost sets the reaction of the user to the post, replacing another
+// one, or takes it back when reaction is nil.
+func (s *service) ReactToPost(ctx context.Context, postID primitive.ObjectID, username string, reaction *models.Reaction) (*models.Post, error) {
+	if reaction == nil {
+		return s.UnlikePost(ctx, postID, username)
+	}
+
+	p, added, err := post.GetPostRepository().SetReaction(ctx, postID, username, *reaction)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyLikeChanged(p, username, reaction)
+	if added {
+		s.notifications.Notify(ctx, &models.Notification{
+			Recipient: p.Username,
+			Actor:     username,
+			Type:      models.NotificationLike,
+			PostID:    &p.ID,
+		})
+	}
+	return p, nil
+}
+
+// ReactToComment sets the reaction of the user to the comment, replacing
+// another one, or takes it back when reaction is nil.
+func (s *service) ReactToComment(ctx context.Context, commentID primitive.ObjectID, username string, reaction *models.Reaction) (*models.Comment, error) {
+	commentRepo := comment.GetCommentRepository()
+	if reaction == nil {
+		return commentRepo.RemoveReaction(ctx, commentID, username)
+	}
+
+	c, added, err := commentRepo.SetReaction(ctx, commentID, username, *reaction)
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		s.notifications.Notify(ctx, &models.Notification{
+			Recipient: c.Username,
+			Actor:     username,
+			Type:      models.NotificationLike,
+			PostID:    &c.PostID,
+			CommentID: &c.ID,
+		})
+	}
+	return c, nil
+}
+
+func (s *service) notifyLikeChanged(p *models.Post, username string, reaction *models.Reaction) {
+	s.broker.Publish(pubsub.PostTopic(pubsub.TopicLikeChanged, p.ID.Hex()), &models.LikeEvent{
+		PostID:    p.ID.Hex(),
+		Username:  username,
+		Liked:     reaction != nil,
+		Reaction:  reaction,
+		LikeCount: len(p.Likes),
+	})
+	s.broker.Publish(pubsub.PostTopic(pubsub.TopicPostUpdated, p.ID.Hex()), p)
 }
@@ -0,0 +1,249 @@
+package like
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/comment"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
+	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// concurrentCalls is how many goroutines race on the same like.
+const concurrentCalls = 20
+
+// testDatabase connects to the MongoDB of TEST_DB_URI and returns a database
+// of its own, dropped by the returned function.
+func testDatabase(t *testing.T) (*mongo.Database, func()) {
+	uri := os.Getenv("TEST_DB_URI")
+	if uri == "" {
+		t.Skip("TEST_DB_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("social-network-test-" + primitive.NewObjectID().Hex())
+	return db, func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}
+}
+
+type testServices struct {
+	likes    LikeService
+	posts    post.PostRepository
+	comments comment.CommentRepository
+	db       *mongo.Database
+	broker   pubsub.Broker
+}
+
+func newTestServices(db *mongo.Database) *testServices {
+	log := logger.NewAppLog()
+	broker := pubsub.NewMemoryBroker(pubsub.Options{BufferSize: 4 * concurrentCalls})
+	notifications := notification.NewNotificationService(db, log, broker)
+	return &testServices{
+		likes:    NewLikeService(log, broker, notifications),
+		posts:    post.NewPostRepository(db, log),
+		comments: comment.NewCommentRepository(db, log),
+		db:       db,
+		broker:   broker,
+	}
+}
+
+func (s *testServices) createPost(t *testing.T) *models.Post {
+	t.Helper()
+	p, err := s.posts.Create(context.Background(), &models.Post{
+		Body:      "post",
+		Username:  "author",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func (s *testServices) countNotifications(t *testing.T) int64 {
+	t.Helper()
+	count, err := s.db.Collection("notifications").CountDocuments(context.Background(), bson.M{"recipient": "author"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// countEvents returns how many events were buffered for sub.
+func countEvents(sub *pubsub.Subscription) int {
+	count := 0
+	for {
+		select {
+		case <-sub.Events():
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+func countLikes(likes []models.Like, username string) int {
+	count := 0
+	for _, like := range likes {
+		if like.Username == username {
+			count++
+		}
+	}
+	return count
+}
+
+// race calls f from concurrentCalls goroutines at once and fails on the
+// first error.
+func race(t *testing.T, f func(i int) error) {
+	t.Helper()
+	start := make(chan struct{})
+	errs := make(chan error, concurrentCalls)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentCalls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs <- f(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLikePostConcurrent(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	s := newTestServices(db)
+	p := s.createPost(t)
+	events := s.broker.Subscribe(context.Background(), pubsub.PostTopic(pubsub.TopicLikeChanged, p.ID.Hex()))
+
+	race(t, func(int) error {
+		_, err := s.likes.LikePost(context.Background(), p.ID, "fan")
+		return err
+	})
+
+	p, err := s.posts.GetByID(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if likes := countLikes(p.Likes, "fan"); likes != 1 || len(p.Likes) != 1 {
+		t.Errorf("%d likes of the user and %d in all, want 1", likes, len(p.Likes))
+	}
+	if count := s.countNotifications(t); count != 1 {
+		t.Errorf("%d notifications, want 1", count)
+	}
+	if count := countEvents(events); count != 1 {
+		t.Errorf("%d like events, want 1", count)
+	}
+}
+
+func TestReactToPostConcurrent(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	s := newTestServices(db)
+	p := s.createPost(t)
+
+	race(t, func(i int) error {
+		reaction := models.Reactions[i%len(models.Reactions)]
+		_, err := s.likes.ReactToPost(context.Background(), p.ID, "fan", &reaction)
+		return err
+	})
+
+	p, err := s.posts.GetByID(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if likes := countLikes(p.Likes, "fan"); likes != 1 || len(p.Likes) != 1 {
+		t.Errorf("%d reactions of the user and %d in all, want 1", likes, len(p.Likes))
+	}
+	if count := s.countNotifications(t); count != 1 {
+		t.Errorf("%d notifications, want 1", count)
+	}
+}
+
+func TestToggleReactionToPostConcurrent(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	s := newTestServices(db)
+	p := s.createPost(t)
+
+	// half of the calls react and the others take the reaction back
+	race(t, func(i int) error {
+		var reaction *models.Reaction
+		if i%2 == 0 {
+			love := models.ReactionLove
+			reaction = &love
+		}
+		_, err := s.likes.ReactToPost(context.Background(), p.ID, "fan", reaction)
+		// the reaction may be taken back between the update and the insert
+		if apperrors.CodeOf(err) == apperrors.CodeConflict {
+			return nil
+		}
+		return err
+	})
+
+	p, err := s.posts.GetByID(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if likes := countLikes(p.Likes, "fan"); likes > 1 || len(p.Likes) != likes {
+		t.Errorf("%d reactions of the user and %d in all, want at most 1", likes, len(p.Likes))
+	}
+}
+
+func TestReactToCommentConcurrent(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	s := newTestServices(db)
+	p := s.createPost(t)
+	c, err := s.comments.Create(context.Background(), &models.Comment{
+		PostID:    p.ID,
+		Body:      "comment",
+		Username:  "author",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	race(t, func(i int) error {
+		reaction := models.Reactions[i%len(models.Reactions)]
+		_, err := s.likes.ReactToComment(context.Background(), c.ID, "fan", &reaction)
+		return err
+	})
+
+	c, err = s.comments.GetByID(context.Background(), c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if likes := countLikes(c.Likes, "fan"); likes != 1 || len(c.Likes) != 1 {
+		t.Errorf("%d reactions of the user and %d in all, want 1", likes, len(c.Likes))
+	}
+	if count := s.countNotifications(t); count != 1 {
+		t.Errorf("%d notifications, want 1", count)
+	}
+}
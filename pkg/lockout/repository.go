@@ -0,0 +1,124 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "login_attempts"
+)
+
+var (
+	lockoutRepo *repository
+)
+
+type LockoutRepository interface {
+	GetByKey(ctx context.Context, key string) (*models.LoginAttempt, error)
+	Reserve(ctx context.Context, key string, failures int, now time.Time, expiresAt time.Time) (bool, error)
+	LockAtLimit(ctx context.Context, key string, limit int, until time.Time) (bool, error)
+	Release(ctx context.Context, key string) error
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewLockoutRepository(db *mongo.Database, log *logger.AppLog) LockoutRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"expiresAt": 1,
+			},
+			// forget the failures once they are old enough
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	ctx := context.Background()
+	lockoutCollection := db.Collection(collectionName)
+	lockoutCollection.Indexes().CreateMany(ctx, mod)
+	lockoutRepo = &repository{
+		Collection: lockoutCollection,
+		Logger:     log,
+	}
+	return lockoutRepo
+}
+
+func GetLockoutRepository() LockoutRepository {
+	return lockoutRepo
+}
+
+// GetByKey returns the attempts of the key or nil when it has none.
+func (r *repository) GetByKey(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{}
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": key}).Decode(attempt); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// Reserve counts an attempt for the key if it still has failures failures,
+// and reports whether it did. A concurrent attempt counted in between makes
+// it fail, so the caller checks the limits again. The document is created by
+// the first attempt.
+func (r *repository) Reserve(ctx context.Context, key string, failures int, now time.Time, expiresAt time.Time) (bool, error) {
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"lastAttempt": now},
+		// a lock keeps the document until it ends
+		"$max": bson.M{"expiresAt": expiresAt},
+	}
+	opts := options.Update().SetUpsert(failures == 0)
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": key, "failures": failures}, update, opts)
+	if err != nil {
+		// the document was created by a concurrent attempt
+		if internal.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return result.MatchedCount > 0 || result.UpsertedCount > 0, nil
+}
+
+// LockAtLimit locks the key until the given time if it has limit failures or
+// more, and reports whether it did.
+func (r *repository) LockAtLimit(ctx context.Context, key string, limit int, until time.Time) (bool, error) {
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": key, "failures": bson.M{"$gte": limit}}, bson.M{
+		"$set": bson.M{"lockedUntil": until},
+		"$max": bson.M{"expiresAt": until},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Release takes back an attempt of the key that succeeded.
+func (r *repository) Release(ctx context.Context, key string) error {
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": key, "failures": bson.M{"$gt": 0}}, bson.M{
+		"$inc": bson.M{"failures": -1},
+	})
+	return err
+}
+
+// Delete forgets the failures of the key and reports whether it had any.
+func (r *repository) Delete(ctx context.Context, key string) (bool, error) {
+	result, err := r.Collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,184 @@
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// BaseDelay is the wait after the first delayed failure, it doubles with
+	// every further failure up to MaxDelay.
+	BaseDelay = time.Second
+	MaxDelay  = 30 * time.Second
+)
+
+// reserveTries bounds how often an attempt is counted again after losing
+// the race to concurrent attempts of the same key.
+const reserveTries = 5
+
+// LockoutService slows down and then locks the logins of a username or from
+// an IP address failing too often. Failures are counted for usernames that
+// do not exist too, so a lock does not tell whether an account exists.
+type LockoutService interface {
+	// Reserve counts the login attempt as failed before the password is
+	// checked, so concurrent guesses can't pass the limits together. It
+	// returns a rate limited error when the login must wait.
+	Reserve(ctx context.Context, username string, ip string) error
+	// Fail locks the username and the IP address if the failed attempt
+	// reached their limit.
+	Fail(ctx context.Context, username string, ip string)
+	// Succeed forgets the failures of the username and takes back the
+	// attempt from the IP address.
+	Succeed(ctx context.Context, username string, ip string)
+	// Reset forgets the failures of the username and reports whether it had any.
+	Reset(ctx context.Context, username string) (bool, error)
+}
+
+type service struct {
+	repository LockoutRepository
+	Logger     *logger.AppLog
+}
+
+func NewLockoutService(db *mongo.Database, log *logger.AppLog) LockoutService {
+	r := NewLockoutRepository(db, log)
+	return &service{repository: r, Logger: log}
+}
+
+func (s *service) Reserve(ctx context.Context, username string, ip string) error {
+	now := time.Now()
+	var reserved []string
+	for _, key := range []string{usernameKey(username), ipKey(ip)} {
+		wait, err := s.reserve(ctx, key, now)
+		if err == nil && wait == 0 {
+			reserved = append(reserved, key)
+			continue
+		}
+
+		// the login does not happen, it must not count for the other key
+		for _, key := range reserved {
+			if err := s.repository.Release(ctx, key); err != nil {
+				s.Logger.Errorf("Release login attempt error %#v", err)
+			}
+		}
+		if err != nil {
+			s.Logger.Errorf("Reserve login attempt error %#v", err)
+			return err
+		}
+		return apperrors.RateLimited(wait)
+	}
+	return nil
+}
+
+// reserve counts an attempt for the key unless it must wait, and returns the
+// wait otherwise.
+func (s *service) reserve(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	for i := 0; i < reserveTries; i++ {
+		attempt, err := s.repository.GetByKey(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt == nil {
+			attempt = &models.LoginAttempt{Key: key}
+		}
+		if wait := waitFor(attempt, now); wait > 0 {
+			return wait, nil
+		}
+		ok, err := s.repository.Reserve(ctx, key, attempt.Failures, now, now.Add(lockoutDuration()))
+		if err != nil || ok {
+			return 0, err
+		}
+	}
+	// concurrent attempts keep winning the race
+	return BaseDelay, nil
+}
+
+// waitFor returns how long the key must wait before its next attempt.
+func waitFor(attempt *models.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+	max := maxAttempts(attempt.Key)
+	// Once the limit is reached a single attempt is allowed after each lock,
+	// its failure locks again. Until then the attempt reaching the limit is
+	// still checked.
+	if attempt.Failures >= max && (attempt.LockedUntil == nil || attempt.LastAttempt.After(*attempt.LockedUntil)) {
+		return BaseDelay
+	}
+	if next := attempt.LastAttempt.Add(delay(attempt.Failures, max)); next.After(now) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// Fail is called after a failed login reserved with Reserve. Errors are only
+// logged, the login fails anyway.
+func (s *service) Fail(ctx context.Context, username string, ip string) {
+	until := time.Now().Add(lockoutDuration())
+	for _, key := range []string{usernameKey(username), ipKey(ip)} {
+		locked, err := s.repository.LockAtLimit(ctx, key, maxAttempts(key), until)
+		if err != nil {
+			s.Logger.Errorf("Lock login error %#v", err)
+			continue
+		}
+		if locked {
+			s.Logger.Warnf("Lock %s after %d failed logins", key, maxAttempts(key))
+		}
+	}
+}
+
+// Succeed is called after a successful login reserved with Reserve.
+func (s *service) Succeed(ctx context.Context, username string, ip string) {
+	if _, err := s.repository.Delete(ctx, usernameKey(username)); err != nil {
+		s.Logger.Errorf("Reset login attempts error %#v", err)
+	}
+	if err := s.repository.Release(ctx, ipKey(ip)); err != nil {
+		s.Logger.Errorf("Release login attempt error %#v", err)
+	}
+}
+
+func (s *service) Reset(ctx context.Context, username string) (bool, error) {
+	return s.repository.Delete(ctx, usernameKey(username))
+}
+
+func usernameKey(username string) string {
+	return "username:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func maxAttempts(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return config.GetConfig().LoginMaxAttemptsPerIP
+	}
+	return config.GetConfig().LoginMaxAttempts
+}
+
+func lockoutDuration() time.Duration {
+	return time.Duration(config.GetConfig().LoginLockout) * time.Minute
+}
+
+// delay is the wait after the last of failures. The first third of the
+// allowed attempts are not delayed so a mistyped password costs nothing.
+func delay(failures int, max int) time.Duration {
+	free := max / 3
+	if failures <= free {
+		return 0
+	}
+	d := BaseDelay
+	for i := free + 1; i < failures && d < MaxDelay; i++ {
+		d *= 2
+	}
+	if d > MaxDelay {
+		d = MaxDelay
+	}
+	return d
+}
@@ -0,0 +1,248 @@
+package lockout
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWaitFor(t *testing.T) {
+	config.Load()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	max := config.GetConfig().LoginMaxAttempts
+	free := max / 3
+
+	tests := []struct {
+		name    string
+		attempt models.LoginAttempt
+		want    time.Duration
+	}{
+		{"first", models.LoginAttempt{}, 0},
+		{"free", models.LoginAttempt{Failures: free, LastAttempt: now}, 0},
+		{"delayed", models.LoginAttempt{Failures: free + 1, LastAttempt: now}, BaseDelay},
+		{"delay doubles", models.LoginAttempt{Failures: free + 2, LastAttempt: now.Add(-time.Second)}, BaseDelay},
+		{"delay over", models.LoginAttempt{Failures: free + 1, LastAttempt: past}, 0},
+		{"locked", models.LoginAttempt{Failures: max, LastAttempt: past, LockedUntil: &future}, time.Minute},
+		{"limit reached", models.LoginAttempt{Failures: max, LastAttempt: past}, BaseDelay},
+		{"lock over", models.LoginAttempt{Failures: max, LastAttempt: past.Add(-time.Hour), LockedUntil: &past}, 0},
+		{"attempt after lock", models.LoginAttempt{Failures: max + 1, LastAttempt: now, LockedUntil: &past}, BaseDelay},
+	}
+	for _, tt := range tests {
+		tt.attempt.Key = usernameKey("user")
+		if got := waitFor(&tt.attempt, now); got != tt.want {
+			t.Errorf("%s: wait %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	want := []time.Duration{0, 0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, MaxDelay, MaxDelay}
+	for failures, d := range want {
+		if got := delay(failures, 10); got != d {
+			t.Errorf("delay after %d failures %s, want %s", failures, got, d)
+		}
+	}
+}
+
+// memoryRepository keeps the attempts in memory under the conditions of the
+// MongoDB repository, so the service can be raced without a database.
+type memoryRepository struct {
+	lock     sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (r *memoryRepository) GetByKey(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *memoryRepository) Reserve(ctx context.Context, key string, failures int, now time.Time, expiresAt time.Time) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	attempt, ok := r.attempts[key]
+	if attempt.Failures != failures || (!ok && failures != 0) {
+		return false, nil
+	}
+	attempt.Key = key
+	attempt.Failures++
+	attempt.LastAttempt = now
+	if expiresAt.After(attempt.ExpiresAt) {
+		attempt.ExpiresAt = expiresAt
+	}
+	r.attempts[key] = attempt
+	return true, nil
+}
+
+func (r *memoryRepository) LockAtLimit(ctx context.Context, key string, limit int, until time.Time) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.Failures < limit {
+		return false, nil
+	}
+	attempt.LockedUntil = &until
+	if until.After(attempt.ExpiresAt) {
+		attempt.ExpiresAt = until
+	}
+	r.attempts[key] = attempt
+	return true, nil
+}
+
+func (r *memoryRepository) Release(ctx context.Context, key string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if attempt, ok := r.attempts[key]; ok && attempt.Failures > 0 {
+		attempt.Failures--
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, key string) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.attempts[key]
+	delete(r.attempts, key)
+	return ok, nil
+}
+
+func newMemoryService() (LockoutService, LockoutRepository) {
+	r := newMemoryRepository()
+	return &service{repository: r, Logger: logger.NewAppLog()}, r
+}
+
+func testDatabase(t *testing.T) (*mongo.Database, func()) {
+	uri := os.Getenv("TEST_DB_URI")
+	if uri == "" {
+		t.Skip("TEST_DB_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("social-network-test-" + primitive.NewObjectID().Hex())
+	return db, func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	}
+}
+
+func TestReserveConcurrent(t *testing.T) {
+	config.Load()
+	s, r := newMemoryService()
+	testReserveConcurrent(t, s, r)
+}
+
+func TestReserveConcurrentMongo(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	config.Load()
+	testReserveConcurrent(t, NewLockoutService(db, logger.NewAppLog()), GetLockoutRepository())
+}
+
+func TestFailLocksAtLimit(t *testing.T) {
+	config.Load()
+	s, r := newMemoryService()
+	testFailLocksAtLimit(t, s, r)
+}
+
+func TestFailLocksAtLimitMongo(t *testing.T) {
+	db, drop := testDatabase(t)
+	defer drop()
+	config.Load()
+	testFailLocksAtLimit(t, NewLockoutService(db, logger.NewAppLog()), GetLockoutRepository())
+}
+
+func testReserveConcurrent(t *testing.T, s LockoutService, r LockoutRepository) {
+	// the guesses run at once, only the free attempts may pass
+	const guesses = 20
+	start := make(chan struct{})
+	results := make(chan error, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results <- s.Reserve(context.Background(), "victim", "10.0.0.1")
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(results)
+
+	allowed := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			allowed++
+		case apperrors.CodeOf(err) != apperrors.CodeRateLimited:
+			t.Fatal(err)
+		}
+	}
+	free := config.GetConfig().LoginMaxAttempts/3 + 1
+	if allowed == 0 || allowed > free {
+		t.Errorf("%d guesses allowed, want 1 to %d", allowed, free)
+	}
+	for _, key := range []string{usernameKey("victim"), ipKey("10.0.0.1")} {
+		attempt, err := r.GetByKey(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempt.Failures != allowed {
+			t.Errorf("%d failures counted for %s, want %d", attempt.Failures, key, allowed)
+		}
+	}
+}
+
+func testFailLocksAtLimit(t *testing.T, s LockoutService, r LockoutRepository) {
+	max := config.GetConfig().LoginMaxAttempts
+
+	now := time.Now()
+	for i := 0; i < max; i++ {
+		// skip the delays between the failures
+		ok, err := r.Reserve(context.Background(), usernameKey("victim"), i, now.Add(-time.Hour), now.Add(time.Hour))
+		if err != nil || !ok {
+			t.Fatalf("reserve %d: %v, %v", i, ok, err)
+		}
+		s.Fail(context.Background(), "victim", "10.0.0.1")
+	}
+
+	err := s.Reserve(context.Background(), "victim", "10.0.0.1")
+	if apperrors.CodeOf(err) != apperrors.CodeRateLimited {
+		t.Fatalf("error %v, want locked", err)
+	}
+	// the refused login is not counted for the IP address
+	if attempt, err := r.GetByKey(context.Background(), ipKey("10.0.0.1")); err != nil || (attempt != nil && attempt.Failures != 0) {
+		t.Errorf("IP attempt %+v, %v after a refused login", attempt, err)
+	}
+	if ok, err := s.Reset(context.Background(), "victim"); err != nil || !ok {
+		t.Fatalf("reset: %v, %v", ok, err)
+	}
+	if err := s.Reserve(context.Background(), "victim", "10.0.0.1"); err != nil {
+		t.Errorf("error %v after reset", err)
+	}
+}
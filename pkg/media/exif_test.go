@@ -0,0 +1,62 @@
+package media
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// exifSegment returns an APP1 segment whose EXIF data only holds the
+// orientation, in the byte order of order.
+func exifSegment(order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	entry := tiff[10:]
+	order.PutUint16(entry, orientationTag)
+	// one SHORT
+	order.PutUint16(entry[2:], 3)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], uint16(orientation))
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(payload)))
+	return append(segment, payload...)
+}
+
+// withExif inserts segment right after the start of image of a JPEG.
+func withExif(jpeg []byte, segment []byte) []byte {
+	data := append([]byte{}, jpeg[:2]...)
+	data = append(data, segment...)
+	return append(data, jpeg[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	soi := []byte{0xFF, 0xD8}
+	eoi := []byte{0xFF, 0xD9}
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"big endian", withExif(append(soi, eoi...), exifSegment(binary.BigEndian, 6)), 6},
+		{"little endian", withExif(append(soi, eoi...), exifSegment(binary.LittleEndian, 8)), 8},
+		{"out of range", withExif(append(soi, eoi...), exifSegment(binary.BigEndian, 9)), 1},
+		{"no exif", append(soi, eoi...), 1},
+		{"not a jpeg", []byte("\x89PNG\x0D\x0A\x1A\x0A"), 1},
+		{"truncated segment", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E', 'x'}, 1},
+		{"bad tiff header", withExif(append(soi, eoi...), []byte{0xFF, 0xE1, 0, 16, 'E', 'x', 'i', 'f', 0, 0, 'X', 'X', 0, 42, 0, 0, 0, 8}), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: orientation %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
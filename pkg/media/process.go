@@ -0,0 +1,96 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	// register the GIF decoder, a GIF is stored as a PNG of its first frame
+	_ "image/gif"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+)
+
+const (
+	// MaxDimension bounds the width and the height of a decoded image.
+	MaxDimension = 10000
+	jpegQuality  = 85
+)
+
+// Sizes are the largest width or height of each stored variant.
+var Sizes = []struct {
+	Size models.ImageSize
+	Max  int
+}{
+	{models.ImageLarge, 1280},
+	{models.ImageMedium, 640},
+	{models.ImageSmall, 160},
+}
+
+// rendition is an encoded variant of an image.
+type rendition struct {
+	size          models.ImageSize
+	width, height int
+	data          []byte
+}
+
+// process decodes an image and renders its variants. The image is checked
+// from its header before decoding so a small file declaring a huge image
+// cannot exhaust the memory. Every variant is re-encoded from the pixels, so
+// none of the metadata of the upload such as EXIF locations is kept.
+func process(data []byte, maxPixels int) ([]rendition, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", apperrors.Validation("Unsupported image")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxDimension || cfg.Height > MaxDimension ||
+		cfg.Width*cfg.Height > maxPixels {
+		return nil, "", apperrors.Validation("Image is too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", apperrors.Validation("Invalid image")
+	}
+	src := toRGBA(img)
+	if format == "jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+
+	contentType := "image/png"
+	encode := func(img image.Image) ([]byte, error) {
+		var buf bytes.Buffer
+		err := png.Encode(&buf, img)
+		return buf.Bytes(), err
+	}
+	// photos stay JPEG, other formats may have transparency
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		encode = func(img image.Image) ([]byte, error) {
+			var buf bytes.Buffer
+			err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+			return buf.Bytes(), err
+		}
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	data, err = encode(src)
+	if err != nil {
+		return nil, "", err
+	}
+	renditions := []rendition{{size: models.ImageOriginal, width: w, height: h, data: data}}
+	for _, s := range Sizes {
+		if w <= s.Max && h <= s.Max {
+			continue
+		}
+		rw, rh := fit(w, h, s.Max)
+		data, err := encode(resize(src, rw, rh))
+		if err != nil {
+			return nil, "", err
+		}
+		renditions = append(renditions, rendition{size: s.Size, width: rw, height: rh, data: data})
+	}
+	return renditions, contentType, nil
+}
@@ -0,0 +1,212 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+)
+
+const testMaxPixels = 25000000
+
+// pngHeader returns the signature and the header of a PNG of w x h without
+// any pixel data, so only image.DecodeConfig can read it.
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 4+13)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	// 8 bit RGBA
+	ihdr[12], ihdr[13] = 8, 6
+
+	data := []byte("\x89PNG\x0D\x0A\x1A\x0A")
+	data = append(data, 0, 0, 0, 13)
+	data = append(data, ihdr...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(ihdr))
+	return append(data, crc...)
+}
+
+// jpegHeader returns the start of a grayscale JPEG of w x h whose scan ends
+// right after its header.
+func jpegHeader(w, h uint16) []byte {
+	data := []byte{0xFF, 0xD8, 0xFF, 0xC0, 0, 11, 8}
+	data = append(data, byte(h>>8), byte(h), byte(w>>8), byte(w))
+	data = append(data, 1, 1, 0x11, 0)
+	return append(data, 0xFF, 0xDA, 0, 8)
+}
+
+// testImage returns a w x h black image whose 16 x 16 top left corner is
+// white, large enough to survive the JPEG compression.
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{0, 0, 0, 255}
+			if x < 16 && y < 16 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessRejectsBeforeDecoding(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png wider than the limit", pngHeader(MaxDimension+1, 1), "Image is too large"},
+		{"png bomb", pngHeader(50000, 50000), "Image is too large"},
+		{"png with too many pixels", pngHeader(6000, 6000), "Image is too large"},
+		{"png of zero pixels", pngHeader(0, 10), "Unsupported image"},
+		{"jpeg taller than the limit", jpegHeader(1, MaxDimension+1), "Image is too large"},
+		{"jpeg with too many pixels", jpegHeader(6000, 6000), "Image is too large"},
+		// a small header passes the checks and only fails to decode
+		{"png without pixels", pngHeader(10, 10), "Invalid image"},
+		{"jpeg without pixels", jpegHeader(10, 10), "Invalid image"},
+		{"not an image", []byte("%PDF-1.4 document"), "Unsupported image"},
+	}
+	for _, tt := range tests {
+		_, _, err := process(tt.data, testMaxPixels)
+		if apperrors.CodeOf(err) != apperrors.CodeValidation || err.Error() != tt.want {
+			t.Errorf("%s: error %v, want %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestProcessOrientsJPEG(t *testing.T) {
+	data := encodeJPEG(t, testImage(64, 32))
+	// where the white corner of the stored image is shown
+	tests := []struct {
+		orientation   int
+		width, height int
+		corner        image.Point
+	}{
+		{1, 64, 32, image.Pt(0, 0)},
+		{2, 64, 32, image.Pt(63, 0)},
+		{3, 64, 32, image.Pt(63, 31)},
+		{4, 64, 32, image.Pt(0, 31)},
+		{5, 32, 64, image.Pt(0, 0)},
+		{6, 32, 64, image.Pt(31, 0)},
+		{7, 32, 64, image.Pt(31, 63)},
+		{8, 32, 64, image.Pt(0, 63)},
+	}
+	for _, tt := range tests {
+		renditions, contentType, err := process(withExif(data, exifSegment(binary.BigEndian, tt.orientation)), testMaxPixels)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		if contentType != "image/jpeg" {
+			t.Errorf("orientation %d: content type %s, want image/jpeg", tt.orientation, contentType)
+		}
+		original := renditions[0]
+		if original.width != tt.width || original.height != tt.height {
+			t.Errorf("orientation %d: size %d x %d, want %d x %d", tt.orientation, original.width, original.height, tt.width, tt.height)
+			continue
+		}
+		img, err := jpeg.Decode(bytes.NewReader(original.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []image.Point{image.Pt(0, 0), image.Pt(tt.width-1, 0), image.Pt(0, tt.height-1), image.Pt(tt.width-1, tt.height-1)} {
+			r, _, _, _ := img.At(p.X, p.Y).RGBA()
+			if white := r > 0x8000; white != (p == tt.corner) {
+				t.Errorf("orientation %d: corner %v is white %t", tt.orientation, p, white)
+			}
+		}
+	}
+}
+
+func TestProcessStripsExif(t *testing.T) {
+	data := withExif(encodeJPEG(t, testImage(2000, 1000)), exifSegment(binary.LittleEndian, 3))
+	renditions, _, err := process(data, testMaxPixels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range renditions {
+		if bytes.Contains(r.data, []byte("Exif\x00\x00")) || bytes.Contains(r.data, []byte{0xFF, 0xE1}) {
+			t.Errorf("%s keeps the EXIF data", r.size)
+		}
+		if orientation := jpegOrientation(r.data); orientation != 1 {
+			t.Errorf("%s has orientation %d", r.size, orientation)
+		}
+	}
+}
+
+func TestProcessRenditions(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		sizes map[models.ImageSize]image.Point
+	}{
+		{"landscape", encodePNG(t, testImage(2000, 1000)), map[models.ImageSize]image.Point{
+			models.ImageOriginal: image.Pt(2000, 1000),
+			models.ImageLarge:    image.Pt(1280, 640),
+			models.ImageMedium:   image.Pt(640, 320),
+			models.ImageSmall:    image.Pt(160, 80),
+		}},
+		{"portrait", encodePNG(t, testImage(300, 900)), map[models.ImageSize]image.Point{
+			models.ImageOriginal: image.Pt(300, 900),
+			models.ImageMedium:   image.Pt(213, 640),
+			models.ImageSmall:    image.Pt(53, 160),
+		}},
+		// nothing is upscaled
+		{"small", encodePNG(t, testImage(100, 50)), map[models.ImageSize]image.Point{
+			models.ImageOriginal: image.Pt(100, 50),
+		}},
+	}
+	for _, tt := range tests {
+		renditions, contentType, err := process(tt.data, testMaxPixels)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if contentType != "image/png" {
+			t.Errorf("%s: content type %s, want image/png", tt.name, contentType)
+		}
+		if len(renditions) != len(tt.sizes) {
+			t.Errorf("%s: %d renditions, want %d", tt.name, len(renditions), len(tt.sizes))
+		}
+		for _, r := range renditions {
+			want, ok := tt.sizes[r.size]
+			if !ok {
+				t.Errorf("%s: unexpected %s rendition", tt.name, r.size)
+				continue
+			}
+			cfg, err := png.DecodeConfig(bytes.NewReader(r.data))
+			if err != nil {
+				t.Fatalf("%s: %s: %v", tt.name, r.size, err)
+			}
+			if r.width != want.X || r.height != want.Y || cfg.Width != want.X || cfg.Height != want.Y {
+				t.Errorf("%s: %s is %d x %d and encoded as %d x %d, want %v", tt.name, r.size, r.width, r.height, cfg.Width, cfg.Height, want)
+			}
+		}
+	}
+}
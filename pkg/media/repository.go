@@ -0,0 +1,105 @@
+package media
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	collectionName = "images"
+)
+
+var (
+	imageRepo *repository
+)
+
+type ImageRepository interface {
+	Create(ctx context.Context, image *models.Image) (*models.Image, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Image, error)
+	GetListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Image, error)
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewImageRepository(db *mongo.Database, log *logger.AppLog) ImageRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "username", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	imageCollection := db.Collection(collectionName)
+	imageCollection.Indexes().CreateMany(ctx, mod)
+	imageRepo = &repository{
+		Collection: imageCollection,
+		Logger:     log,
+	}
+	return imageRepo
+}
+
+func GetImageRepository() ImageRepository {
+	return imageRepo
+}
+
+func (r *repository) Create(ctx context.Context, image *models.Image) (*models.Image, error) {
+	image.CreatedAt = time.Now().Format(time.RFC3339)
+	if _, err := r.Collection.InsertOne(ctx, image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Image, error) {
+	image := &models.Image{}
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(image); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found image")
+		}
+		return nil, err
+	}
+	return image, nil
+}
+
+// GetListByIDs returns the images in the order of ids, skipping missing ones.
+func (r *repository) GetListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Image, error) {
+	images := []*models.Image{}
+	if len(ids) == 0 {
+		return images, nil
+	}
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &images); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Image, len(images))
+	for _, image := range images {
+		byID[image.ID] = image
+	}
+	ordered := make([]*models.Image, 0, len(images))
+	for _, id := range ids {
+		if image, ok := byID[id]; ok {
+			ordered = append(ordered, image)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,90 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, max int
+		wantW     int
+		wantH     int
+	}{
+		{100, 50, 200, 100, 50},
+		{200, 200, 200, 200, 200},
+		{2000, 1000, 1280, 1280, 640},
+		{1000, 2000, 640, 320, 640},
+		{3000, 2000, 160, 160, 106},
+		{10000, 1, 160, 160, 1},
+		{1, 10000, 160, 1, 160},
+	}
+	for _, tt := range tests {
+		w, h := fit(tt.w, tt.h, tt.max)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d, %d) = %d x %d, want %d x %d", tt.w, tt.h, tt.max, w, h, tt.wantW, tt.wantH)
+		}
+		if w > tt.max || h > tt.max {
+			t.Errorf("fit(%d, %d, %d) = %d x %d is larger than %d", tt.w, tt.h, tt.max, w, h, tt.max)
+		}
+	}
+}
+
+func TestResizeAverages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// the left half is black and the right half white
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+	dst := resize(src, 2, 1)
+	if dst.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("resized to %v, want 2 x 1", dst.Bounds())
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{0, 0, 0, 0}) {
+		t.Errorf("left pixel %v, want black", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("right pixel %v, want white", got)
+	}
+	if resize(src, 4, 2) != src {
+		t.Error("resized an image to its own size")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 3 x 2 image whose pixels are numbered in reading order
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Pix[i*4] = uint8(i + 1)
+	}
+	tests := []struct {
+		orientation int
+		// the numbers of the upright image in reading order
+		want          []uint8
+		width, height int
+	}{
+		{1, []uint8{1, 2, 3, 4, 5, 6}, 3, 2},
+		{2, []uint8{3, 2, 1, 6, 5, 4}, 3, 2},
+		{3, []uint8{6, 5, 4, 3, 2, 1}, 3, 2},
+		{4, []uint8{4, 5, 6, 1, 2, 3}, 3, 2},
+		{5, []uint8{1, 4, 2, 5, 3, 6}, 2, 3},
+		{6, []uint8{4, 1, 5, 2, 6, 3}, 2, 3},
+		{7, []uint8{6, 3, 5, 2, 4, 1}, 2, 3},
+		{8, []uint8{3, 6, 2, 5, 1, 4}, 2, 3},
+	}
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+		if dst.Bounds().Dx() != tt.width || dst.Bounds().Dy() != tt.height {
+			t.Errorf("orientation %d: size %v, want %d x %d", tt.orientation, dst.Bounds(), tt.width, tt.height)
+			continue
+		}
+		for i, want := range tt.want {
+			if got := dst.Pix[i*4]; got != want {
+				t.Errorf("orientation %d: pixel %d is %d, want %d", tt.orientation, i, got, want)
+			}
+		}
+	}
+}
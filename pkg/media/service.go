@@ -0,0 +1,97 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MediaService interface {
+	UploadImage(ctx context.Context, username string, file *storage.File) (*models.Image, error)
+	GetImages(ctx context.Context, ids []primitive.ObjectID) ([]*models.Image, error)
+}
+
+type service struct {
+	repository ImageRepository
+	storage    storage.Storage
+	Logger     *logger.AppLog
+}
+
+func NewMediaService(db *mongo.Database, log *logger.AppLog, store storage.Storage) MediaService {
+	r := NewImageRepository(db, log)
+	return &service{repository: r, storage: store, Logger: log}
+}
+
+// UploadImage stores the image re-encoded in every size that is not larger
+// than the original.
+func (s *service) UploadImage(ctx context.Context, username string, file *storage.File) (*models.Image, error) {
+	maxSize := int64(config.GetConfig().MaxUploadSize)
+	if file.Size > maxSize {
+		return nil, apperrors.Validation("File %s is larger than %d bytes", file.Filename, maxSize)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(file.Content, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, apperrors.Validation("File %s is larger than %d bytes", file.Filename, maxSize)
+	}
+
+	renditions, contentType, err := process(data, config.GetConfig().MaxImagePixels)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &models.Image{
+		ID:       primitive.NewObjectID(),
+		Username: username,
+		Width:    renditions[0].width,
+		Height:   renditions[0].height,
+	}
+	ext := ".png"
+	if contentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	for _, r := range renditions {
+		variant := models.ImageVariant{
+			Size:   r.size,
+			Key:    fmt.Sprintf("images/%s/%s%s", image.ID.Hex(), r.size, ext),
+			Width:  r.width,
+			Height: r.height,
+		}
+		if err := s.storage.Put(ctx, variant.Key, bytes.NewReader(r.data), int64(len(r.data)), contentType); err != nil {
+			s.Logger.Errorf("Store image error %#v", err)
+			s.deleteVariants(ctx, image.Variants)
+			return nil, apperrors.Wrap(err, apperrors.CodeInternal, "Cannot store image %s", file.Filename)
+		}
+		image.Variants = append(image.Variants, variant)
+	}
+
+	if _, err := s.repository.Create(ctx, image); err != nil {
+		s.deleteVariants(ctx, image.Variants)
+		return nil, err
+	}
+	return image, nil
+}
+
+func (s *service) GetImages(ctx context.Context, ids []primitive.ObjectID) ([]*models.Image, error) {
+	return s.repository.GetListByIDs(ctx, ids)
+}
+
+func (s *service) deleteVariants(ctx context.Context, variants []models.ImageVariant) {
+	for _, v := range variants {
+		if err := s.storage.Delete(ctx, v.Key); err != nil {
+			s.Logger.Errorf("Delete image %s error %#v", v.Key, err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+// Attachment is a file of a post kept in the storage under Key.
+type Attachment struct {
+	Key         string `bson:"key" json:"key"`
+	Filename    string `bson:"filename" json:"filename"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	Size        int64  `bson:"size" json:"size"`
+}
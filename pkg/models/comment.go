@@ -3,8 +3,15 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type Comment struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Body      string             `bson:"body" json:"body"`
-	Username  string             `bson:"username" json:"username"`
-	CreatedAt string             `bson:"createdAt" json:"createdAt"`
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID  `bson:"postId" json:"postId"`
+	Body      string              `bson:"body" json:"body"`
+	Username  string              `bson:"username" json:"username"`
+	CreatedAt string              `bson:"createdAt" json:"createdAt"`
+	ParentID  *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId"`
+	Depth     int                 `bson:"depth" json:"depth"`
+	Deleted   bool                `bson:"deleted,omitempty" json:"deleted"`
+	EditedAt  *string             `bson:"editedAt,omitempty" json:"editedAt"`
+	Revisions []Revision          `bson:"revisions,omitempty" json:"revisions"`
+	Likes     []Like              `bson:"likes,omitempty" json:"likes"`
 }
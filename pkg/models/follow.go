@@ -0,0 +1,10 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type Follow struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Follower  string             `bson:"follower" json:"follower"`
+	Followee  string             `bson:"followee" json:"followee"`
+	CreatedAt string             `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImageSize string
+
+const (
+	ImageOriginal ImageSize = "ORIGINAL"
+	ImageLarge    ImageSize = "LARGE"
+	ImageMedium   ImageSize = "MEDIUM"
+	ImageSmall    ImageSize = "SMALL"
+)
+
+func (s ImageSize) IsValid() bool {
+	switch s {
+	case ImageOriginal, ImageLarge, ImageMedium, ImageSmall:
+		return true
+	}
+	return false
+}
+
+func (s *ImageSize) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return apperrors.Validation("enums must be strings")
+	}
+
+	*s = ImageSize(str)
+	if !s.IsValid() {
+		return apperrors.Validation("%s is not a valid ImageSize", str)
+	}
+	return nil
+}
+
+func (s ImageSize) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(string(s)))
+}
+
+// ImageVariant is a stored rendition of an image, Key locates it in the storage.
+type ImageVariant struct {
+	Size   ImageSize `bson:"size" json:"size"`
+	Key    string    `bson:"key" json:"key"`
+	Width  int       `bson:"width" json:"width"`
+	Height int       `bson:"height" json:"height"`
+}
+
+// Image is an uploaded picture with its variants ordered from the largest.
+// Sizes larger than the original are not stored.
+type Image struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username  string             `bson:"username" json:"username"`
+	Width     int                `bson:"width" json:"width"`
+	Height    int                `bson:"height" json:"height"`
+	Variants  []ImageVariant     `bson:"variants" json:"variants"`
+	CreatedAt string             `bson:"createdAt" json:"createdAt"`
+}
+
+// Variant returns the variant of the size, or the smallest larger one when
+// the image was too small to have it.
+func (i *Image) Variant(size ImageSize) *ImageVariant {
+	var found *ImageVariant
+	for j := range i.Variants {
+		if imageSizeOrder[i.Variants[j].Size] < imageSizeOrder[size] {
+			break
+		}
+		found = &i.Variants[j]
+	}
+	return found
+}
+
+var imageSizeOrder = map[ImageSize]int{
+	ImageSmall:    1,
+	ImageMedium:   2,
+	ImageLarge:    3,
+	ImageOriginal: 4,
+}
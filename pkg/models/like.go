@@ -6,4 +6,5 @@ type Like struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Username  string             `bson:"username" json:"username"`
 	CreatedAt string             `bson:"createdAt" json:"createdAt"`
+	Reaction  Reaction           `bson:"reaction,omitempty" json:"reaction"`
 }
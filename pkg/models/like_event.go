@@ -0,0 +1,11 @@
+package models
+
+// LikeEvent tells subscribers that a user reacted to a post or took the
+// reaction back, Reaction is nil then.
+type LikeEvent struct {
+	PostID    string    `json:"postId"`
+	Username  string    `json:"username"`
+	Liked     bool      `json:"liked"`
+	Reaction  *Reaction `json:"reaction"`
+	LikeCount int       `json:"likeCount"`
+}
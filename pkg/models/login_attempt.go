@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt counts the failed logins for a username or from an IP
+// address, identified by Key. An attempt is counted before the password is
+// checked and taken back when the login succeeds. It is removed once
+// ExpiresAt passes.
+type LoginAttempt struct {
+	Key         string     `bson:"_id" json:"key"`
+	Failures    int        `bson:"failures" json:"failures"`
+	LastAttempt time.Time  `bson:"lastAttempt" json:"lastAttempt"`
+	LockedUntil *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil"`
+	ExpiresAt   time.Time  `bson:"expiresAt" json:"expiresAt"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationType string
+
+const (
+	NotificationLike    NotificationType = "LIKE"
+	NotificationComment NotificationType = "COMMENT"
+	NotificationReply   NotificationType = "REPLY"
+	NotificationMention NotificationType = "MENTION"
+	NotificationFollow  NotificationType = "FOLLOW"
+)
+
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case NotificationLike, NotificationComment, NotificationReply, NotificationMention, NotificationFollow:
+		return true
+	}
+	return false
+}
+
+func (t *NotificationType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return apperrors.Validation("enums must be strings")
+	}
+
+	*t = NotificationType(str)
+	if !t.IsValid() {
+		return apperrors.Validation("%s is not a valid NotificationType", str)
+	}
+	return nil
+}
+
+func (t NotificationType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(string(t)))
+}
+
+// Notification tells the recipient that the actor did something involving
+// them. PostID and CommentID point at what it is about when there is one.
+type Notification struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Recipient string              `bson:"recipient" json:"recipient"`
+	Actor     string              `bson:"actor" json:"actor"`
+	Type      NotificationType    `bson:"type" json:"type"`
+	PostID    *primitive.ObjectID `bson:"postId,omitempty" json:"postId"`
+	CommentID *primitive.ObjectID `bson:"commentId,omitempty" json:"commentId"`
+	Read      bool                `bson:"read" json:"read"`
+	CreatedAt string              `bson:"createdAt" json:"createdAt"`
+}
+
+type NotificationEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *Notification `json:"node"`
+}
+
+type NotificationConnection struct {
+	Edges       []*NotificationEdge `json:"edges"`
+	PageInfo    *PageInfo           `json:"pageInfo"`
+	UnreadCount int                 `json:"unreadCount"`
+}
@@ -3,10 +3,15 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type Post struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Body      string             `bson:"body" json:"body"`
-	CreatedAt string             `bson:"createdAt" json:"createdAt"`
-	Username  string             `bson:"username" json:"username"`
-	Comments  []Comment          `bson:"comments,omitempty" json:"comments"`
-	Likes     []Like             `bson:"likes,omitempty" json:"likes"`
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Body        string               `bson:"body" json:"body"`
+	CreatedAt   string               `bson:"createdAt" json:"createdAt"`
+	Username    string               `bson:"username" json:"username"`
+	Likes       []Like               `bson:"likes,omitempty" json:"likes"`
+	EditedAt    *string              `bson:"editedAt,omitempty" json:"editedAt"`
+	Revisions   []Revision           `bson:"revisions,omitempty" json:"revisions"`
+	Tags        []string             `bson:"tags,omitempty" json:"tags"`
+	Mentions    []string             `bson:"mentions,omitempty" json:"mentions"`
+	Attachments []Attachment         `bson:"attachments,omitempty" json:"attachments"`
+	ImageIDs    []primitive.ObjectID `bson:"images,omitempty" json:"imageIds"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+)
+
+type Reaction string
+
+const (
+	ReactionLike  Reaction = "LIKE"
+	ReactionLove  Reaction = "LOVE"
+	ReactionLaugh Reaction = "LAUGH"
+	ReactionSad   Reaction = "SAD"
+	ReactionAngry Reaction = "ANGRY"
+)
+
+// Reactions lists the reactions in the order they are counted.
+var Reactions = []Reaction{ReactionLike, ReactionLove, ReactionLaugh, ReactionSad, ReactionAngry}
+
+func (r Reaction) IsValid() bool {
+	for _, reaction := range Reactions {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reaction) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return apperrors.Validation("enums must be strings")
+	}
+
+	*r = Reaction(str)
+	if !r.IsValid() {
+		return apperrors.Validation("%s is not a valid Reaction", str)
+	}
+	return nil
+}
+
+// MarshalGQL writes likes stored before reactions existed as LIKE.
+func (r Reaction) MarshalGQL(w io.Writer) {
+	if r == "" {
+		r = ReactionLike
+	}
+	fmt.Fprint(w, strconv.Quote(string(r)))
+}
+
+type ReactionCount struct {
+	Reaction Reaction `json:"reaction"`
+	Count    int      `json:"count"`
+}
+
+// CountReactions returns how many of the likes have each reaction, leaving
+// out reactions nobody used.
+func CountReactions(likes []Like) []*ReactionCount {
+	counts := map[Reaction]int{}
+	for _, like := range likes {
+		reaction := like.Reaction
+		if reaction == "" {
+			reaction = ReactionLike
+		}
+		counts[reaction]++
+	}
+	result := []*ReactionCount{}
+	for _, reaction := range Reactions {
+		if counts[reaction] > 0 {
+			result = append(result, &ReactionCount{Reaction: reaction, Count: counts[reaction]})
+		}
+	}
+	return result
+}
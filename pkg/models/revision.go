@@ -0,0 +1,10 @@
+package models
+
+// MaxRevisions is how many previous bodies are kept for a post or comment.
+const MaxRevisions = 20
+
+// Revision is a previous body of an edited post or comment.
+type Revision struct {
+	Body      string `bson:"body" json:"body"`
+	CreatedAt string `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type PostSearchEdge struct {
+	Cursor  string  `json:"cursor"`
+	Node    *Post   `json:"node"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type PostSearchConnection struct {
+	Edges    []*PostSearchEdge `json:"edges"`
+	PageInfo *PageInfo         `json:"pageInfo"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a login of a user on a device, identified by its refresh token.
+// Only hashes of the refresh tokens are stored.
+type Session struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	Username        string             `bson:"username" json:"username"`
+	TokenHash       string             `bson:"tokenHash" json:"-"`
+	UsedTokenHashes []string           `bson:"usedTokenHashes,omitempty" json:"-"`
+	ExpiresAt       time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt       *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt"`
+	CreatedAt       string             `bson:"createdAt" json:"createdAt"`
+}
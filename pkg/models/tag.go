@@ -0,0 +1,6 @@
+package models
+
+type TrendingTag struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int    `bson:"count" json:"count"`
+}
@@ -1,12 +1,79 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "USER"
+	RoleModerator Role = "MODERATOR"
+	RoleAdmin     Role = "ADMIN"
+)
+
+var roleLevels = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Includes reports whether the role grants everything other grants.
+// Users created before roles existed have an empty role and count as USER.
+func (r Role) Includes(other Role) bool {
+	if r == "" {
+		r = RoleUser
+	}
+	return roleLevels[r] >= roleLevels[other]
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+func (r *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return apperrors.Validation("enums must be strings")
+	}
+
+	*r = Role(str)
+	if !r.IsValid() {
+		return apperrors.Validation("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (r Role) MarshalGQL(w io.Writer) {
+	if r == "" {
+		r = RoleUser
+	}
+	fmt.Fprint(w, strconv.Quote(string(r)))
+}
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email     string             `bson:"email" json:"email"`
-	Username  string             `bson:"username" json:"username"`
-	Password  string             `bson:"password" json:"-"`
-	Token     string             `bson:"-" json:"token"`
-	CreatedAt string             `bson:"createdAt" json:"createdAt"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email        string             `bson:"email" json:"email"`
+	Username     string             `bson:"username" json:"username"`
+	Password     string             `bson:"password" json:"-"`
+	Token        string             `bson:"-" json:"token"`
+	RefreshToken string             `bson:"-" json:"-"`
+	Role         Role               `bson:"role,omitempty" json:"role"`
+	CreatedAt    string             `bson:"createdAt" json:"createdAt"`
+}
+
+// HasRole reports whether the user has the role or a higher one.
+func (u *User) HasRole(role Role) bool {
+	return u.Role.Includes(role)
+}
+
+// IsAdmin reports whether the user may act on content of other users.
+func (u *User) IsAdmin() bool {
+	return u.HasRole(RoleAdmin)
 }
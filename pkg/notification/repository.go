@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	collectionName = "notifications"
+)
+
+var (
+	notificationRepo *repository
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, n *models.Notification) (*models.Notification, error)
+	GetList(ctx context.Context, recipient string, unreadOnly bool, page *internal.Page) ([]*models.Notification, bool, error)
+	CountUnread(ctx context.Context, recipient string) (int, error)
+	MarkRead(ctx context.Context, recipient string, ids []primitive.ObjectID) (int, error)
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewNotificationRepository(db *mongo.Database, log *logger.AppLog) NotificationRepository {
+	mod := []mongo.IndexModel{
+		{
+			// notifications of a user are paginated newest first
+			Keys: bson.D{
+				{Key: "recipient", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "recipient", Value: 1},
+				{Key: "read", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	notificationCollection := db.Collection(collectionName)
+	notificationCollection.Indexes().CreateMany(ctx, mod)
+	notificationRepo = &repository{
+		Collection: notificationCollection,
+		Logger:     log,
+	}
+	return notificationRepo
+}
+
+func GetNotificationRepository() NotificationRepository {
+	return notificationRepo
+}
+
+func (r *repository) Create(ctx context.Context, n *models.Notification) (*models.Notification, error) {
+	n.CreatedAt = time.Now().Format(time.RFC3339)
+	result, err := r.Collection.InsertOne(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	n.ID = result.InsertedID.(primitive.ObjectID)
+	return n, nil
+}
+
+func (r *repository) GetList(ctx context.Context, recipient string, unreadOnly bool, page *internal.Page) ([]*models.Notification, bool, error) {
+	notifications := []*models.Notification{}
+	filter := bson.M{"recipient": recipient}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	cursor, err := r.Collection.Find(ctx, page.Filter(filter), page.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, false, err
+	}
+
+	size, hasMore := page.Size(len(notifications))
+	notifications = notifications[:size]
+	if page.Backward {
+		for i, j := 0, len(notifications)-1; i < j; i, j = i+1, j-1 {
+			notifications[i], notifications[j] = notifications[j], notifications[i]
+		}
+	}
+	return notifications, hasMore, nil
+}
+
+func (r *repository) CountUnread(ctx context.Context, recipient string) (int, error) {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"recipient": recipient, "read": false})
+	return int(count), err
+}
+
+// MarkRead marks the given notifications of the recipient as read, or all of
+// them when ids is empty, and returns how many changed.
+func (r *repository) MarkRead(ctx context.Context, recipient string, ids []primitive.ObjectID) (int, error) {
+	filter := bson.M{"recipient": recipient, "read": false}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	result, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
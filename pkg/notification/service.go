@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type NotificationService interface {
+	Notify(ctx context.Context, n *models.Notification)
+	GetNotifications(ctx context.Context, username string, unreadOnly bool, page *internal.Page) (*models.NotificationConnection, error)
+	MarkRead(ctx context.Context, username string, ids []primitive.ObjectID) (int, error)
+}
+
+type service struct {
+	repository NotificationRepository
+	broker     pubsub.Broker
+	Logger     *logger.AppLog
+}
+
+func NewNotificationService(db *mongo.Database, log *logger.AppLog, broker pubsub.Broker) NotificationService {
+	r := NewNotificationRepository(db, log)
+	return &service{repository: r, broker: broker, Logger: log}
+}
+
+// Notify stores the notification and sends it to the recipient. Users are not
+// notified of their own actions. A failure is only logged, it must not fail
+// the action that caused the notification.
+func (s *service) Notify(ctx context.Context, n *models.Notification) {
+	if n.Recipient == "" || n.Recipient == n.Actor {
+		return
+	}
+	if _, err := s.repository.Create(ctx, n); err != nil {
+		s.Logger.Errorf("Create notification error %#v", err)
+		return
+	}
+	s.broker.Publish(pubsub.UserTopic(pubsub.TopicNotification, n.Recipient), n)
+}
+
+func (s *service) GetNotifications(ctx context.Context, username string, unreadOnly bool, page *internal.Page) (*models.NotificationConnection, error) {
+	notifications, hasMore, err := s.repository.GetList(ctx, username, unreadOnly, page)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repository.CountUnread(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*models.NotificationEdge, len(notifications))
+	cursors := make([]string, len(notifications))
+	for i, n := range notifications {
+		cursors[i] = internal.EncodeCursor(n.ID)
+		edges[i] = &models.NotificationEdge{Cursor: cursors[i], Node: n}
+	}
+	return &models.NotificationConnection{
+		Edges:       edges,
+		PageInfo:    page.PageInfo(hasMore, cursors),
+		UnreadCount: unread,
+	}, nil
+}
+
+// MarkRead marks the notifications of the user as read, all of them when ids
+// is empty, and returns how many are still unread.
+func (s *service) MarkRead(ctx context.Context, username string, ids []primitive.ObjectID) (int, error) {
+	if _, err := s.repository.MarkRead(ctx, username, ids); err != nil {
+		s.Logger.Errorf("Mark notifications read error %#v", err)
+		return 0, err
+	}
+	return s.repository.CountUnread(ctx, username)
+}
@@ -0,0 +1,80 @@
+package persisted
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/gomodule/redigo/redis"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+)
+
+const (
+	DefaultRedisPrefix = "social-network:apq:"
+	// DefaultTTL is how long Redis keeps a query, a client sends an expired
+	// query again when the server does not know its hash.
+	DefaultTTL = 24 * time.Hour
+)
+
+// NewMemoryCache keeps the size most recently used queries of this replica.
+func NewMemoryCache(size int) graphql.Cache {
+	return lru.New(size)
+}
+
+// RedisCache keeps the queries in Redis so a query registered on one replica
+// is known by all of them.
+type RedisCache struct {
+	pool   *redis.Pool
+	prefix string
+	ttl    time.Duration
+	Logger *logger.AppLog
+}
+
+var _ graphql.Cache = &RedisCache{}
+
+// NewRedisCache keeps the queries in the Redis of pool, which the cache does
+// not close.
+func NewRedisCache(pool *redis.Pool, prefix string, ttl time.Duration, log *logger.AppLog) *RedisCache {
+	return &RedisCache{
+		pool:   pool,
+		prefix: prefix,
+		ttl:    ttl,
+		Logger: log,
+	}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		c.Logger.Errorf("Get persisted query error %#v", err)
+		return nil, false
+	}
+	defer conn.Close()
+
+	query, err := redis.String(conn.Do("GET", c.prefix+key))
+	if err != nil {
+		if err != redis.ErrNil {
+			c.Logger.Errorf("Get persisted query error %#v", err)
+		}
+		return nil, false
+	}
+	return query, true
+}
+
+func (c *RedisCache) Add(ctx context.Context, key string, value interface{}) {
+	query, ok := value.(string)
+	if !ok {
+		return
+	}
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		c.Logger.Errorf("Add persisted query error %#v", err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", c.prefix+key, query, "EX", int(c.ttl.Seconds())); err != nil {
+		c.Logger.Errorf("Add persisted query error %#v", err)
+	}
+}
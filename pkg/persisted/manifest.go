@@ -0,0 +1,95 @@
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errOperationNotAllowed = "OPERATION_NOT_ALLOWED"
+
+// Manifest maps the SHA-256 hash of each allowed query to its text, the
+// format written by web/scripts/persisted-queries.js.
+type Manifest map[string]string
+
+// LoadManifest reads a manifest and checks that every hash matches its query.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("Invalid manifest %s: %v", path, err)
+	}
+	for hash, query := range manifest {
+		if QueryHash(query) != hash {
+			return nil, fmt.Errorf("Hash %s of manifest %s does not match its query", hash, path)
+		}
+	}
+	return manifest, nil
+}
+
+// QueryHash is the hash identifying a query, as computed by Apollo clients.
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Allowlist only executes the operations of the manifest. Clients may send
+// the full query or only its hash the way automatic persisted queries do,
+// the query of a hash is taken from the manifest.
+type Allowlist struct {
+	Manifest Manifest
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = Allowlist{}
+
+func (a Allowlist) ExtensionName() string {
+	return "Allowlist"
+}
+
+func (a Allowlist) Validate(schema graphql.ExecutableSchema) error {
+	if a.Manifest == nil {
+		return fmt.Errorf("Allowlist.Manifest can not be nil")
+	}
+	return nil
+}
+
+func (a Allowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	if rawParams.Query != "" {
+		if _, ok := a.Manifest[QueryHash(rawParams.Query)]; !ok {
+			return notAllowed()
+		}
+		return nil
+	}
+
+	extension, _ := rawParams.Extensions["persistedQuery"].(map[string]interface{})
+	hash, _ := extension["sha256Hash"].(string)
+	if hash == "" {
+		// let the executor report the missing query
+		return nil
+	}
+	query, ok := a.Manifest[hash]
+	if !ok {
+		return notAllowed()
+	}
+	rawParams.Query = query
+	return nil
+}
+
+func notAllowed() *gqlerror.Error {
+	err := gqlerror.Errorf("Operation is not allowed")
+	errcode.Set(err, errOperationNotAllowed)
+	return err
+}
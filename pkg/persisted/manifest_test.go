@@ -0,0 +1,125 @@
+package persisted
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const testQuery = "query Posts { getPosts { edges { node { id } } } }"
+
+// writeManifest writes content to a manifest file in dir.
+func writeManifest(t *testing.T, dir string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "manifest.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persisted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, `{"`+QueryHash(testQuery)+`": "`+testQuery+`"}`)
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 1 || manifest[QueryHash(testQuery)] != testQuery {
+		t.Errorf("loaded %v", manifest)
+	}
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persisted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not json", "query Posts { getPosts }"},
+		{"truncated", `{"` + QueryHash(testQuery) + `": "` + testQuery},
+		{"list", `["` + testQuery + `"]`},
+		{"not a query", `{"` + QueryHash(testQuery) + `": 1}`},
+		{"wrong hash", `{"` + QueryHash("query Other { getUsers { id } }") + `": "` + testQuery + `"}`},
+		{"upper case hash", `{"` + strings.ToUpper(QueryHash(testQuery)) + `": "` + testQuery + `"}`},
+	}
+	for _, tt := range tests {
+		if manifest, err := LoadManifest(writeManifest(t, dir, tt.content)); err == nil {
+			t.Errorf("%s: loaded %v", tt.name, manifest)
+		}
+	}
+	if _, err := LoadManifest(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loaded a missing manifest")
+	}
+}
+
+func TestQueryHash(t *testing.T) {
+	// the hex SHA-256 of the query text, as sent by the clients
+	if got, want := QueryHash("{ __typename }"), "7f56e67dd21ab3f30d1ff8b7bed08893f0a0db86449836189b361dd1e56ddb4b"; got != want {
+		t.Errorf("hash %s, want %s", got, want)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allowlist := Allowlist{Manifest: Manifest{QueryHash(testQuery): testQuery}}
+	persisted := func(hash interface{}) map[string]interface{} {
+		return map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash}}
+	}
+	tests := []struct {
+		name    string
+		params  graphql.RawParams
+		allowed bool
+		query   string
+	}{
+		{"allowed query", graphql.RawParams{Query: testQuery}, true, testQuery},
+		{"unknown query", graphql.RawParams{Query: "{ getUsers { id } }"}, false, ""},
+		{"changed query", graphql.RawParams{Query: testQuery + " "}, false, ""},
+		{"allowed hash", graphql.RawParams{Extensions: persisted(QueryHash(testQuery))}, true, testQuery},
+		{"unknown hash", graphql.RawParams{Extensions: persisted(QueryHash("{ getUsers { id } }"))}, false, ""},
+		// the query must match the manifest, not only its claimed hash
+		{"query with allowed hash", graphql.RawParams{Query: "{ getUsers { id } }", Extensions: persisted(QueryHash(testQuery))}, false, ""},
+		{"hash of another type", graphql.RawParams{Extensions: persisted(1)}, true, ""},
+		{"no query", graphql.RawParams{}, true, ""},
+	}
+	for _, tt := range tests {
+		params := tt.params
+		err := allowlist.MutateOperationParameters(context.Background(), &params)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s: error %v, want allowed %t", tt.name, err, tt.allowed)
+			continue
+		}
+		if err != nil {
+			if err.Extensions["code"] != errOperationNotAllowed {
+				t.Errorf("%s: code %v, want %s", tt.name, err.Extensions["code"], errOperationNotAllowed)
+			}
+			continue
+		}
+		if params.Query != tt.query {
+			t.Errorf("%s: query %q, want %q", tt.name, params.Query, tt.query)
+		}
+	}
+}
+
+func TestAllowlistValidate(t *testing.T) {
+	if err := (Allowlist{}).Validate(nil); err == nil {
+		t.Error("accepted a nil manifest")
+	}
+	if err := (Allowlist{Manifest: Manifest{}}).Validate(nil); err != nil {
+		t.Error(err)
+	}
+}
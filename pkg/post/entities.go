@@ -0,0 +1,78 @@
+package post
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/trinhdaiphuc/social-network/pkg/user"
+)
+
+const (
+	// MaxTagLength bounds the length of a hashtag, longer ones are cut.
+	MaxTagLength = 50
+	// MaxMentions bounds how many mentions of a post are resolved.
+	MaxMentions = 20
+)
+
+var (
+	// A hashtag or mention must start the body or follow a character that
+	// cannot be part of a word, so emails and URL fragments are ignored.
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@/])@([\p{L}\p{N}_.\-]+)`)
+)
+
+// ParseTags returns the distinct hashtags of body, lower cased and without #.
+func ParseTags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := NormalizeTag(match[1])
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeTag returns the stored form of a hashtag given with or without #.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if runes := []rune(tag); len(runes) > MaxTagLength {
+		tag = string(runes[:MaxTagLength])
+	}
+	return tag
+}
+
+// ParseMentions returns the distinct usernames mentioned in body without @.
+// Trailing dots and dashes are punctuation rather than part of the name.
+func ParseMentions(body string) []string {
+	mentions := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		mentions = append(mentions, name)
+	}
+	return mentions
+}
+
+// resolveMentions keeps the mentions naming an existing user, the others stay
+// plain text in the body.
+func resolveMentions(ctx context.Context, body string) []string {
+	mentions := []string{}
+	for _, name := range ParseMentions(body) {
+		if len(mentions) == MaxMentions {
+			break
+		}
+		if u, err := user.GetUserRepository().GetByUsername(ctx, name); err == nil {
+			mentions = append(mentions, u.Username)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,82 @@
+package post
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"start of body", "#go is fun", []string{"go"}},
+		{"several", "I like #go and #rust", []string{"go", "rust"}},
+		{"trailing punctuation", "so #fast! really #fast, #quick.", []string{"fast", "quick"}},
+		{"in brackets", "(#paren) [#square]", []string{"paren", "square"}},
+		{"case folded duplicates", "#Go #GO #go", []string{"go"}},
+		{"unicode", "#café #日本 #Ümlaut", []string{"café", "日本", "ümlaut"}},
+		{"digits and underscores", "#2021 #go_lang", []string{"2021", "go_lang"}},
+		{"url fragment", "see https://example.com/page#section and example.com/#top", []string{}},
+		{"html entity", "it&#39;s", []string{}},
+		{"inside a word", "c#sharp", []string{}},
+		{"glued tags", "#one#two", []string{"one"}},
+		{"bare hash", "# heading and #", []string{}},
+		{"empty", "", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ParseTags(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: tags of %q = %q, want %q", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"start of body", "@alice hi", []string{"alice"}},
+		{"several", "cc @alice, @bob", []string{"alice", "bob"}},
+		{"trailing punctuation", "thanks @alice. and @bob- and @carol!", []string{"alice", "bob", "carol"}},
+		{"dots and dashes inside", "@john.doe @mary-jane", []string{"john.doe", "mary-jane"}},
+		{"duplicates", "@alice @alice", []string{"alice"}},
+		// usernames keep their case
+		{"case kept", "@Alice @alice", []string{"Alice", "alice"}},
+		{"unicode", "@José", []string{"José"}},
+		{"email", "mail a@b.com or alice@example.com", []string{}},
+		{"url", "https://example.com/@alice", []string{}},
+		{"double at", "@@alice", []string{}},
+		{"bare at", "meet @ noon", []string{}},
+		{"only punctuation", "@... @--", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ParseMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mentions of %q = %q, want %q", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"#Go", "go"},
+		{"go", "go"},
+		{"  #GoLang ", "golang"},
+		{"#ÉTÉ", "été"},
+		{"#", ""},
+		{strings.Repeat("a", MaxTagLength+10), strings.Repeat("a", MaxTagLength)},
+		// cut by characters rather than bytes
+		{strings.Repeat("日", MaxTagLength+1), strings.Repeat("日", MaxTagLength)},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.tag); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
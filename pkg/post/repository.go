@@ -3,7 +3,9 @@ package post
 import (
 	"context"
 	"fmt"
+	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,19 +20,25 @@ const (
 
 var (
 	postRepo *repository
+
+	ErrNotFound  = apperrors.NotFound("Not found post")
+	ErrForbidden = apperrors.Forbidden("Action not allowed")
 )
 
 type PostRepository interface {
-	GetList(ctx context.Context) ([]*models.Post, error)
+	GetList(ctx context.Context, page *internal.Page) ([]*models.Post, bool, error)
+	GetListByUsernames(ctx context.Context, usernames []string, page *internal.Page) ([]*models.Post, bool, error)
+	GetListByTag(ctx context.Context, tag string, page *internal.Page) ([]*models.Post, bool, error)
+	GetTrendingTags(ctx context.Context, since time.Time, limit int) ([]*models.TrendingTag, error)
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Post, error)
+	GetListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error)
+	Search(ctx context.Context, query string, limit int) ([]*models.Post, []float64, error)
 	DeleteByID(ctx context.Context, id primitive.ObjectID) (string, error)
+	UpdateBody(ctx context.Context, id primitive.ObjectID, oldBody string, edit *models.Post, revision models.Revision) (*models.Post, error)
 	Create(ctx context.Context, p *models.Post) (*models.Post, error)
-	CreateComment(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error)
-	GetCommentByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error)
-	DeleteCommentByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error)
-	FindLikeByUsername(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error)
-	CreateLike(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error)
-	DeleteLikeByID(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error)
+	CreateLike(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, bool, error)
+	DeleteLikeByID(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, bool, error)
+	SetReaction(ctx context.Context, postID primitive.ObjectID, username string, reaction models.Reaction) (*models.Post, bool, error)
 }
 
 type repository struct {
@@ -39,8 +47,32 @@ type repository struct {
 }
 
 func NewPostRepository(db *mongo.Database, log *logger.AppLog) PostRepository {
+	mod := []mongo.IndexModel{
+		{
+			// home feed lists posts of several authors newest first
+			Keys: bson.D{
+				{Key: "username", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			// posts of a hashtag newest first
+			Keys: bson.D{
+				{Key: "tags", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			// full text search over post bodies
+			Keys: bson.D{{Key: "body", Value: "text"}},
+		},
+	}
+
+	ctx := context.Background()
+	postCollection := db.Collection(collectionName)
+	postCollection.Indexes().CreateMany(ctx, mod)
 	postRepo = &repository{
-		Collection: db.Collection(collectionName),
+		Collection: postCollection,
 		Logger:     log,
 	}
 	return postRepo
@@ -50,24 +82,81 @@ func GetPostRepository() PostRepository {
 	return postRepo
 }
 
-func (r *repository) GetList(ctx context.Context) ([]*models.Post, error) {
-	var posts []*models.Post
+func (r *repository) GetList(ctx context.Context, page *internal.Page) ([]*models.Post, bool, error) {
+	return r.findPage(ctx, bson.M{}, page)
+}
 
-	cursor, err := r.Collection.Find(ctx, bson.D{})
-	defer cursor.Close(ctx)
+func (r *repository) GetListByUsernames(ctx context.Context, usernames []string, page *internal.Page) ([]*models.Post, bool, error) {
+	if len(usernames) == 0 {
+		return []*models.Post{}, false, nil
+	}
+	return r.findPage(ctx, bson.M{"username": bson.M{"$in": usernames}}, page)
+}
+
+func (r *repository) GetListByTag(ctx context.Context, tag string, page *internal.Page) ([]*models.Post, bool, error) {
+	return r.findPage(ctx, bson.M{"tags": tag}, page)
+}
+
+// GetTrendingTags counts the hashtags of the posts created since the given
+// time. ObjectIDs start with their creation time so the window is a range on
+// the _id index.
+func (r *repository) GetTrendingTags(ctx context.Context, since time.Time, limit int) ([]*models.TrendingTag, error) {
+	tags := []*models.TrendingTag{}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":  bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)},
+			"tags": bson.M{"$exists": true},
+		}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	cursor.All(ctx, &posts)
-	return posts, nil
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *repository) findPage(ctx context.Context, filter bson.M, page *internal.Page) ([]*models.Post, bool, error) {
+	posts := []*models.Post{}
+
+	cursor, err := r.Collection.Find(ctx, page.Filter(filter), page.FindOptions())
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, false, err
+	}
+
+	size, hasMore := page.Size(len(posts))
+	posts = posts[:size]
+	if page.Backward {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+	return posts, hasMore, nil
 }
 
 func (r *repository) Create(ctx context.Context, p *models.Post) (*models.Post, error) {
 	post := &models.Post{
-		Body:      p.Body,
-		CreatedAt: time.Now().Format(time.RFC3339),
-		Username:  p.Username,
+		Body:        p.Body,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		Username:    p.Username,
+		Tags:        p.Tags,
+		Mentions:    p.Mentions,
+		Attachments: p.Attachments,
+		ImageIDs:    p.ImageIDs,
 	}
 
 	resullt, err := r.Collection.InsertOne(ctx, post)
@@ -85,112 +174,192 @@ func (r *repository) GetByID(ctx context.Context, id primitive.ObjectID) (*model
 	post := &models.Post{}
 	if err := result.Decode(post); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 	return post, nil
 }
 
-func (r *repository) DeleteByID(ctx context.Context, id primitive.ObjectID) (string, error) {
-	filter := bson.M{"_id": id}
+func (r *repository) GetListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error) {
+	posts := []*models.Post{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
 
-	result, err := r.Collection.DeleteOne(ctx, filter)
+	cursor, err := r.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return fmt.Sprintf("deleted %v documents", result.DeletedCount), nil
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
 }
 
-func (r *repository) CreateComment(ctx context.Context, postID primitive.ObjectID, comment *models.Comment) (*models.Post, error) {
-	filter := bson.M{"_id": postID}
-	post := &models.Post{}
-	update := bson.M{"$addToSet": bson.M{"comments": bson.M{"$each": []models.Comment{*comment}}}}
-	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(post)
+// Search returns the posts matching the text query, most relevant first,
+// together with their text scores.
+func (r *repository) Search(ctx context.Context, query string, limit int) ([]*models.Post, []float64, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"$text": bson.M{"$search": query}}, opts)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
-		}
-		return nil, err
+		return nil, nil, err
 	}
-	return post, err
+	defer cursor.Close(ctx)
+
+	var hits []struct {
+		models.Post `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, nil, err
+	}
+
+	posts := make([]*models.Post, len(hits))
+	scores := make([]float64, len(hits))
+	for i := range hits {
+		posts[i] = &hits[i].Post
+		scores[i] = hits[i].Score
+	}
+	return posts, scores, nil
 }
 
-func (r *repository) GetCommentByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error) {
-	filter := bson.M{"_id": postID, "comments._id": commentID}
-	post := &models.Post{}
-	err := r.Collection.FindOne(ctx, filter).Decode(post)
+func (r *repository) DeleteByID(ctx context.Context, id primitive.ObjectID) (string, error) {
+	filter := bson.M{"_id": id}
+
+	result, err := r.Collection.DeleteOne(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
-		}
-		return nil, err
+		return "", err
 	}
-	return post, err
+	return fmt.Sprintf("deleted %v documents", result.DeletedCount), nil
 }
 
-func (r *repository) DeleteCommentByID(ctx context.Context, postID primitive.ObjectID, commentID primitive.ObjectID) (*models.Post, error) {
-	filter := bson.M{"_id": postID}
+// UpdateBody replaces the body with its tags and mentions only if the body
+// still is oldBody, so concurrent edits cannot lose a revision.
+func (r *repository) UpdateBody(ctx context.Context, id primitive.ObjectID, oldBody string, edit *models.Post, revision models.Revision) (*models.Post, error) {
+	filter := bson.M{"_id": id, "body": oldBody}
 	post := &models.Post{}
 	update := bson.M{
-		"$pull": bson.M{
-			"comments": bson.M{
-				"_id": commentID,
+		"$set": bson.M{
+			"body":     edit.Body,
+			"tags":     edit.Tags,
+			"mentions": edit.Mentions,
+			"editedAt": time.Now().Format(time.RFC3339),
+		},
+		"$push": bson.M{
+			"revisions": bson.M{
+				"$each":  []models.Revision{revision},
+				"$slice": -models.MaxRevisions,
 			},
 		},
 	}
 	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(post)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
-		}
-		return nil, err
-	}
-	return post, err
-}
-
-func (r *repository) FindLikeByUsername(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error) {
-	filter := bson.M{"_id": postID, "likes.username": username}
-	post := &models.Post{}
-	if err := r.Collection.FindOne(ctx, filter).Decode(post); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
+			return nil, apperrors.Conflict("Post was changed, please try again")
 		}
 		return nil, err
 	}
 	return post, nil
 }
 
-func (r *repository) CreateLike(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error) {
-	filter := bson.M{"_id": postID}
+// CreateLike adds a LIKE of the user unless the user already reacted to the
+// post, and reports whether it was added. The condition and the push are a
+// single update so concurrent calls add one like at most.
+func (r *repository) CreateLike(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, bool, error) {
 	post := &models.Post{}
-	update := bson.M{"$addToSet": bson.M{"likes": bson.M{"$each": []models.Like{
-		{
-			ID:        primitive.NewObjectID(),
-			Username:  username,
-			CreatedAt: time.Now().Format(time.RFC3339),
-		},
-	}}}}
+	filter, update := notReactedFilter(postID, username), pushReaction(username, models.ReactionLike)
 	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(post)
+	if err == mongo.ErrNoDocuments {
+		// the post does not exist or the user already reacted
+		post, err = r.GetByID(ctx, postID)
+		return post, false, err
+	}
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
-		}
-		return nil, err
+		return nil, false, err
 	}
-	return post, err
+	return post, true, nil
 }
 
-func (r *repository) DeleteLikeByID(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, error) {
-	filter := bson.M{"_id": postID}
+// DeleteLikeByID removes the reaction of the user to the post and reports
+// whether there was one.
+func (r *repository) DeleteLikeByID(ctx context.Context, postID primitive.ObjectID, username string) (*models.Post, bool, error) {
+	filter := reactedFilter(postID, username)
 	post := &models.Post{}
 	update := bson.M{"$pull": bson.M{"likes": bson.M{"username": username}}}
 	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(post)
+	if err == mongo.ErrNoDocuments {
+		// the post does not exist or the user did not react
+		post, err = r.GetByID(ctx, postID)
+		return post, false, err
+	}
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("Not found post")
+		return nil, false, err
+	}
+	return post, true, nil
+}
+
+// SetReaction replaces the reaction of the user to the post or adds it, and
+// reports whether it was added. Both updates are guarded on the reaction
+// existing or not, so concurrent calls never add a user twice.
+func (r *repository) SetReaction(ctx context.Context, postID primitive.ObjectID, username string, reaction models.Reaction) (*models.Post, bool, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	for attempt := 0; attempt < 2; attempt++ {
+		post := &models.Post{}
+		filter, update := reactedFilter(postID, username), setReaction(reaction)
+		err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(post)
+		if err == nil {
+			return post, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+
+		filter, update = notReactedFilter(postID, username), pushReaction(username, reaction)
+		err = r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(post)
+		if err == nil {
+			return post, true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+		// Either the post does not exist or the user reacted in between
+		if _, err := r.GetByID(ctx, postID); err != nil {
+			return nil, false, err
 		}
-		return nil, err
 	}
-	return post, err
+	return nil, false, apperrors.Conflict("Post was changed, please try again")
+}
+
+// reactedFilter matches the post when the user reacted to it.
+func reactedFilter(postID primitive.ObjectID, username string) bson.M {
+	return bson.M{"_id": postID, "likes.username": username}
+}
+
+// notReactedFilter matches the post when the user did not react to it yet.
+// Pushing a reaction through it never adds the user twice.
+func notReactedFilter(postID primitive.ObjectID, username string) bson.M {
+	return bson.M{"_id": postID, "likes.username": bson.M{"$ne": username}}
+}
+
+// pushReaction adds a reaction of the user, to be matched by notReactedFilter.
+func pushReaction(username string, reaction models.Reaction) bson.M {
+	return bson.M{"$push": bson.M{"likes": models.Like{
+		ID:        primitive.NewObjectID(),
+		Username:  username,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Reaction:  reaction,
+	}}}
+}
+
+// setReaction changes the reaction of the user matched by reactedFilter.
+func setReaction(reaction models.Reaction) bson.M {
+	return bson.M{"$set": bson.M{"likes.$.reaction": reaction}}
 }
@@ -0,0 +1,56 @@
+package post
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The race-free likes rely on the condition and the write being one update,
+// these tests check the documents of those updates without a database.
+
+func TestReactionFilters(t *testing.T) {
+	id := primitive.NewObjectID()
+	if got, want := reactedFilter(id, "fan"), (bson.M{"_id": id, "likes.username": "fan"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("reacted filter %v, want %v", got, want)
+	}
+	// the push is guarded on the user being absent from the likes
+	want := bson.M{"_id": id, "likes.username": bson.M{"$ne": "fan"}}
+	if got := notReactedFilter(id, "fan"); !reflect.DeepEqual(got, want) {
+		t.Errorf("not reacted filter %v, want %v", got, want)
+	}
+}
+
+func TestPushReaction(t *testing.T) {
+	data, err := bson.Marshal(pushReaction("fan", models.ReactionLove))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	if elements, _ := raw.Elements(); len(elements) != 1 {
+		t.Errorf("update has %d operators, want only $push", len(elements))
+	}
+
+	// the filters find the pushed like through the same field names
+	path := append([]string{"$push"}, strings.Split("likes.username", ".")...)
+	if username, ok := raw.Lookup(path...).StringValueOK(); !ok || username != "fan" {
+		t.Errorf("pushed username %q at %v, want fan", username, path)
+	}
+	if reaction, ok := raw.Lookup("$push", "likes", "reaction").StringValueOK(); !ok || reaction != string(models.ReactionLove) {
+		t.Errorf("pushed reaction %q, want %s", reaction, models.ReactionLove)
+	}
+	if _, ok := raw.Lookup("$push", "likes", "_id").ObjectIDOK(); !ok {
+		t.Error("pushed like without ID")
+	}
+}
+
+func TestSetReaction(t *testing.T) {
+	want := bson.M{"$set": bson.M{"likes.$.reaction": models.ReactionSad}}
+	if got := setReaction(models.ReactionSad); !reflect.DeepEqual(got, want) {
+		t.Errorf("update %v, want %v", got, want)
+	}
+}
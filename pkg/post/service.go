@@ -2,56 +2,301 @@ package post
 
 import (
 	"context"
-	"fmt"
-	"github.com/trinhdaiphuc/social-network/common"
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/media"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/notification"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"github.com/trinhdaiphuc/social-network/pkg/storage"
 	"github.com/trinhdaiphuc/social-network/pkg/user"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+const (
+	// TrendingTagsLimit is the number of tags returned by GetTrendingTags.
+	TrendingTagsLimit = 10
+	// MaxTrendingWindow bounds how far back trending tags are counted.
+	MaxTrendingWindow = 30 * 24 * time.Hour
+	// MaxAttachments bounds the number of files of a post.
+	MaxAttachments = 4
+	// MaxImages bounds the number of images of a post.
+	MaxImages = 10
 )
 
 type PostService interface {
-	GetPosts(ctx context.Context) ([]*models.Post, error)
+	GetPosts(ctx context.Context, page *internal.Page) (*models.PostConnection, error)
 	GetPost(ctx context.Context, id primitive.ObjectID) (*models.Post, error)
-	DeletePost(ctx context.Context, id primitive.ObjectID) (string, error)
-	CreatePost(ctx context.Context, p *models.Post) (*models.Post, error)
+	GetPostsByTag(ctx context.Context, tag string, page *internal.Page) (*models.PostConnection, error)
+	GetTrendingTags(ctx context.Context, window time.Duration) ([]*models.TrendingTag, error)
+	DeletePost(ctx context.Context, id primitive.ObjectID, u *models.User) (string, error)
+	CreatePost(ctx context.Context, p *models.Post, files []*storage.File) (*models.Post, error)
+	EditPost(ctx context.Context, id primitive.ObjectID, username string, body string) (*models.Post, error)
+}
+
+// CommentRemover deletes the comments of a post. The comment package
+// depends on this one, so its repository is given to the service.
+type CommentRemover interface {
+	DeleteByPostID(ctx context.Context, postID primitive.ObjectID) (int, error)
 }
 
 type service struct {
-	repository PostRepository
-	Logger     *logger.AppLog
-	NewPostChannel chan *models.Post
+	repository    PostRepository
+	broker        pubsub.Broker
+	notifications notification.NotificationService
+	storage       storage.Storage
+	comments      CommentRemover
+	Logger        *logger.AppLog
 }
 
-func NewPostService(db *mongo.Database, log *logger.AppLog) PostService {
+func NewPostService(db *mongo.Database, log *logger.AppLog, broker pubsub.Broker, notifications notification.NotificationService, store storage.Storage, comments CommentRemover) PostService {
 	r := NewPostRepository(db, log)
-	return &service{repository: r, Logger: log}
+	return &service{repository: r, broker: broker, notifications: notifications, storage: store, comments: comments, Logger: log}
 }
 
-func (p *service) GetPosts(ctx context.Context) ([]*models.Post, error) {
-	return p.repository.GetList(ctx)
+func (p *service) GetPosts(ctx context.Context, page *internal.Page) (*models.PostConnection, error) {
+	posts, hasMore, err := p.repository.GetList(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostConnection(page, posts, hasMore), nil
 }
 
 func (p *service) GetPost(ctx context.Context, id primitive.ObjectID) (*models.Post, error) {
 	return p.repository.GetByID(ctx, id)
 }
 
-func (p *service) DeletePost(ctx context.Context, id primitive.ObjectID) (string, error) {
-	return p.repository.DeleteByID(ctx, id)
+func (p *service) GetPostsByTag(ctx context.Context, tag string, page *internal.Page) (*models.PostConnection, error) {
+	tag = NormalizeTag(tag)
+	if tag == "" {
+		return nil, apperrors.Validation("Tag must not be empty")
+	}
+	posts, hasMore, err := p.repository.GetListByTag(ctx, tag, page)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostConnection(page, posts, hasMore), nil
+}
+
+// GetTrendingTags returns the most used hashtags of the posts created within
+// the window.
+func (p *service) GetTrendingTags(ctx context.Context, window time.Duration) ([]*models.TrendingTag, error) {
+	if window <= 0 {
+		return nil, apperrors.Validation("Window must be positive")
+	}
+	if window > MaxTrendingWindow {
+		window = MaxTrendingWindow
+	}
+	tags, err := p.repository.GetTrendingTags(ctx, time.Now().Add(-window), TrendingTagsLimit)
+	if err != nil {
+		p.Logger.Errorf("Get trending tags error %#v", err)
+		return nil, err
+	}
+	return tags, nil
 }
 
-func (p *service) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+// DeletePost deletes a post of the user, admins can delete any post.
+func (p *service) DeletePost(ctx context.Context, id primitive.ObjectID, u *models.User) (string, error) {
+	post, err := p.repository.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if post.Username != u.Username {
+		if !u.IsAdmin() {
+			return "", ErrForbidden
+		}
+		p.Logger.Infof("Admin %s deletes post %s of %s", u.Username, id.Hex(), post.Username)
+	}
+	result, err := p.repository.DeleteByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	// the comments are unreachable without their post, failing to delete
+	// them only leaves garbage
+	if _, err := p.comments.DeleteByPostID(ctx, id); err != nil {
+		p.Logger.Errorf("Delete comments of post %s error %#v", id.Hex(), err)
+	}
+	p.broker.Publish(pubsub.TopicPostDeleted, id.Hex())
+	p.deleteAttachments(ctx, post.Attachments)
+	return result, nil
+}
+
+func (p *service) CreatePost(ctx context.Context, post *models.Post, files []*storage.File) (*models.Post, error) {
 	if _, err := user.GetUserRepository().GetByUsername(ctx, post.Username); err != nil {
-		return nil, fmt.Errorf("Not found username")
+		return nil, apperrors.NotFound("Not found username")
 	}
-	newPost, err := p.repository.Create(ctx, post)
+	if strings.TrimSpace(post.Body) == "" && len(files) == 0 && len(post.ImageIDs) == 0 {
+		return nil, apperrors.Validation("Body must not be empty")
+	}
+	if err := checkImages(ctx, post); err != nil {
+		return nil, err
+	}
+	attachments, err := p.storeAttachments(ctx, files)
 	if err != nil {
 		return nil, err
 	}
-	for _, observer := range common.AddChannelObserver {
-		observer <- newPost
-		//This sends new post to client via socket
+	post.Attachments = attachments
+	post.Tags = ParseTags(post.Body)
+	post.Mentions = resolveMentions(ctx, post.Body)
+	newPost, err := p.repository.Create(ctx, post)
+	if err != nil {
+		p.deleteAttachments(ctx, attachments)
+		return nil, err
 	}
+	// This sends new post to client via socket
+	p.broker.Publish(pubsub.TopicNewPost, newPost)
+	p.notifyMentions(ctx, newPost, newPost.Mentions)
 	return newPost, nil
 }
+
+func (p *service) EditPost(ctx context.Context, id primitive.ObjectID, username string, body string) (*models.Post, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, apperrors.Validation("Body must not be empty")
+	}
+	post, err := p.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Username != username {
+		return nil, ErrForbidden
+	}
+	if post.Body == body {
+		return post, nil
+	}
+
+	revision := models.Revision{Body: post.Body, CreatedAt: post.CreatedAt}
+	if post.EditedAt != nil {
+		revision.CreatedAt = *post.EditedAt
+	}
+	mentioned := map[string]bool{}
+	for _, name := range post.Mentions {
+		mentioned[name] = true
+	}
+	edit := &models.Post{
+		Body:     body,
+		Tags:     ParseTags(body),
+		Mentions: resolveMentions(ctx, body),
+	}
+	post, err = p.repository.UpdateBody(ctx, id, post.Body, edit, revision)
+	if err != nil {
+		return nil, err
+	}
+	p.broker.Publish(pubsub.PostTopic(pubsub.TopicPostUpdated, id.Hex()), post)
+
+	// Only users mentioned by the edit are notified, the others already were.
+	added := []string{}
+	for _, name := range post.Mentions {
+		if !mentioned[name] {
+			added = append(added, name)
+		}
+	}
+	p.notifyMentions(ctx, post, added)
+	return post, nil
+}
+
+// checkImages only lets a post reference distinct images uploaded by its author.
+func checkImages(ctx context.Context, post *models.Post) error {
+	if len(post.ImageIDs) == 0 {
+		return nil
+	}
+	if len(post.ImageIDs) > MaxImages {
+		return apperrors.Validation("A post can have at most %d images", MaxImages)
+	}
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range post.ImageIDs {
+		if seen[id] {
+			return apperrors.Validation("Image %s is referenced twice", id.Hex())
+		}
+		seen[id] = true
+	}
+
+	images, err := media.GetImageRepository().GetListByIDs(ctx, post.ImageIDs)
+	if err != nil {
+		return err
+	}
+	if len(images) != len(post.ImageIDs) {
+		return apperrors.NotFound("Not found image")
+	}
+	for _, image := range images {
+		if image.Username != post.Username {
+			return apperrors.NotFound("Not found image")
+		}
+	}
+	return nil
+}
+
+// storeAttachments validates every file before storing any of them, and
+// removes the stored ones again when one fails.
+func (p *service) storeAttachments(ctx context.Context, files []*storage.File) ([]models.Attachment, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if len(files) > MaxAttachments {
+		return nil, apperrors.Validation("A post can have at most %d attachments", MaxAttachments)
+	}
+
+	maxSize := int64(config.GetConfig().MaxUploadSize)
+	exts := make([]string, len(files))
+	for i, f := range files {
+		ext, err := storage.Validate(f, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		exts[i] = ext
+	}
+
+	attachments := make([]models.Attachment, 0, len(files))
+	for i, f := range files {
+		attachment := models.Attachment{
+			Key:         "attachments/" + primitive.NewObjectID().Hex() + exts[i],
+			Filename:    f.Filename,
+			ContentType: f.ContentType,
+			Size:        f.Size,
+		}
+		if err := p.storage.Put(ctx, attachment.Key, f.Content, f.Size, f.ContentType); err != nil {
+			p.Logger.Errorf("Store attachment error %#v", err)
+			p.deleteAttachments(ctx, attachments)
+			return nil, apperrors.Wrap(err, apperrors.CodeInternal, "Cannot store file %s", f.Filename)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (p *service) deleteAttachments(ctx context.Context, attachments []models.Attachment) {
+	for _, a := range attachments {
+		if err := p.storage.Delete(ctx, a.Key); err != nil {
+			p.Logger.Errorf("Delete attachment %s error %#v", a.Key, err)
+		}
+	}
+}
+
+func (p *service) notifyMentions(ctx context.Context, post *models.Post, usernames []string) {
+	for _, name := range usernames {
+		p.notifications.Notify(ctx, &models.Notification{
+			Recipient: name,
+			Actor:     post.Username,
+			Type:      models.NotificationMention,
+			PostID:    &post.ID,
+		})
+	}
+}
+
+// NewPostConnection wraps a page of posts into a Relay connection.
+func NewPostConnection(page *internal.Page, posts []*models.Post, hasMore bool) *models.PostConnection {
+	edges := make([]*models.PostEdge, len(posts))
+	cursors := make([]string, len(posts))
+	for i, post := range posts {
+		cursors[i] = internal.EncodeCursor(post.ID)
+		edges[i] = &models.PostEdge{Cursor: cursors[i], Node: post}
+	}
+	return &models.PostConnection{
+		Edges:    edges,
+		PageInfo: page.PageInfo(hasMore, cursors),
+	}
+}
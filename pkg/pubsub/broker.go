@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Broker delivers events published on a topic to its subscribers.
+type Broker interface {
+	Publish(topic string, event interface{})
+	// PublishLocal only delivers the event to subscribers of this replica.
+	PublishLocal(topic string, event interface{})
+	Subscribe(ctx context.Context, topic string) *Subscription
+	Metrics() Metrics
+	Close() error
+}
+
+// Message is a JSON encoded event.
+type Message []byte
+
+// Decode decodes the event into v.
+func (m Message) Decode(v interface{}) error {
+	return json.Unmarshal(m, v)
+}
+
+// Policy decides what happens to an event published to a subscriber whose buffer is full.
+type Policy int
+
+const (
+	// DropOldest discards the oldest buffered event to make room for the new one.
+	DropOldest Policy = iota
+	// DropNewest discards the new event.
+	DropNewest
+	// Disconnect closes the subscription of the slow subscriber.
+	Disconnect
+)
+
+const DefaultBufferSize = 16
+
+type Options struct {
+	BufferSize int
+	Policy     Policy
+}
+
+// ParsePolicy returns the policy named drop-oldest, drop-newest or disconnect.
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "drop-oldest":
+		return DropOldest, nil
+	case "drop-newest":
+		return DropNewest, nil
+	case "disconnect":
+		return Disconnect, nil
+	}
+	return DropOldest, fmt.Errorf("Unknown slow subscriber policy %q", name)
+}
@@ -0,0 +1,26 @@
+package pubsub
+
+import "strings"
+
+type filterBroker struct {
+	Broker
+	names map[string]bool
+}
+
+// WithoutTopics returns a broker that drops the events published on the
+// named topics, including the topics of single posts. The events can still be
+// published locally, which is how the post watcher delivers them.
+func WithoutTopics(b Broker, names ...string) Broker {
+	f := &filterBroker{Broker: b, names: make(map[string]bool)}
+	for _, name := range names {
+		f.names[name] = true
+	}
+	return f
+}
+
+func (f *filterBroker) Publish(topic string, event interface{}) {
+	if f.names[strings.SplitN(topic, ":", 2)[0]] {
+		return
+	}
+	f.Broker.Publish(topic, event)
+}
@@ -0,0 +1,160 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryBroker fans out published events to the subscribers of a topic in
+// this process. Publishing never blocks on slow subscribers, their buffers
+// overflow according to the policy instead.
+type MemoryBroker struct {
+	lock    sync.RWMutex
+	topics  map[string]map[*Subscription]struct{}
+	options Options
+
+	published    uint64
+	dropped      uint64
+	disconnected uint64
+}
+
+func NewMemoryBroker(options Options) *MemoryBroker {
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	return &MemoryBroker{
+		topics:  make(map[string]map[*Subscription]struct{}),
+		options: options,
+	}
+}
+
+// Subscribe registers a subscriber on topic until ctx is done or the
+// subscription is closed.
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string) *Subscription {
+	sub := &Subscription{
+		topic:  topic,
+		events: make(chan Message, b.options.BufferSize),
+		broker: b,
+	}
+
+	b.lock.Lock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*Subscription]struct{})
+	}
+	b.topics[topic][sub] = struct{}{}
+	b.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+	return sub
+}
+
+// Publish sends the event to the subscribers of topic.
+func (b *MemoryBroker) Publish(topic string, event interface{}) {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	b.dispatch(topic, msg)
+}
+
+func (b *MemoryBroker) PublishLocal(topic string, event interface{}) {
+	b.Publish(topic, event)
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+func (b *MemoryBroker) dispatch(topic string, msg Message) {
+	atomic.AddUint64(&b.published, 1)
+
+	var slow []*Subscription
+	b.lock.RLock()
+	for sub := range b.topics[topic] {
+		if !sub.deliver(msg, b.options.Policy) {
+			slow = append(slow, sub)
+		}
+	}
+	b.lock.RUnlock()
+
+	for _, sub := range slow {
+		atomic.AddUint64(&b.disconnected, 1)
+		sub.Close()
+	}
+}
+
+func (b *MemoryBroker) remove(sub *Subscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.topics[sub.topic], sub)
+	if len(b.topics[sub.topic]) == 0 {
+		delete(b.topics, sub.topic)
+	}
+}
+
+type Subscription struct {
+	topic  string
+	events chan Message
+	broker *MemoryBroker
+
+	lock   sync.Mutex
+	closed bool
+}
+
+// Events returns the channel of events, it is closed when the subscription ends.
+func (s *Subscription) Events() <-chan Message {
+	return s.events
+}
+
+// Close unregisters the subscription and closes its channel.
+func (s *Subscription) Close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.events)
+	s.lock.Unlock()
+
+	s.broker.remove(s)
+}
+
+// deliver buffers the event and returns false when the subscriber should be
+// disconnected.
+func (s *Subscription) deliver(msg Message, policy Policy) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return true
+	}
+
+	select {
+	case s.events <- msg:
+		return true
+	default:
+	}
+
+	switch policy {
+	case DropOldest:
+		select {
+		case <-s.events:
+		default:
+		}
+		select {
+		case s.events <- msg:
+		default:
+		}
+		atomic.AddUint64(&s.broker.dropped, 1)
+	case DropNewest:
+		atomic.AddUint64(&s.broker.dropped, 1)
+	case Disconnect:
+		return false
+	}
+	return true
+}
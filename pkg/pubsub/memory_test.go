@@ -0,0 +1,151 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryBrokerConcurrent(t *testing.T) {
+	for _, policy := range []Policy{DropOldest, DropNewest, Disconnect} {
+		b := NewMemoryBroker(Options{BufferSize: 4, Policy: policy})
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			topic := fmt.Sprintf("topic-%d", i%3)
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				ctx, cancel := context.WithCancel(context.Background())
+				sub := b.Subscribe(ctx, topic)
+				for j := 0; j < 10; j++ {
+					select {
+					case <-sub.Events():
+					case <-time.After(time.Millisecond):
+					}
+				}
+				// half of the subscribers close, the others end with their context
+				if i%2 == 0 {
+					sub.Close()
+				}
+				cancel()
+			}(i)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					b.Publish(topic, j)
+				}
+			}()
+		}
+		wg.Wait()
+		b.Close()
+
+		// the subscriptions are closed by their context asynchronously
+		waitFor(t, func() bool { return b.Metrics().Subscribers == 0 })
+		if published := b.Metrics().Published; published != 20*50 {
+			t.Errorf("policy %d: published %d events, want %d", policy, published, 20*50)
+		}
+	}
+}
+
+func TestMemoryBrokerDropOldest(t *testing.T) {
+	b := NewMemoryBroker(Options{BufferSize: 2, Policy: DropOldest})
+	sub := b.Subscribe(context.Background(), "topic")
+	for i := 1; i <= 3; i++ {
+		b.Publish("topic", i)
+	}
+
+	if got := receive(t, sub, 2); got[0] != 2 || got[1] != 3 {
+		t.Errorf("received %v, want [2 3]", got)
+	}
+	if dropped := b.Metrics().Dropped; dropped != 1 {
+		t.Errorf("dropped %d events, want 1", dropped)
+	}
+}
+
+func TestMemoryBrokerDropNewest(t *testing.T) {
+	b := NewMemoryBroker(Options{BufferSize: 2, Policy: DropNewest})
+	sub := b.Subscribe(context.Background(), "topic")
+	for i := 1; i <= 3; i++ {
+		b.Publish("topic", i)
+	}
+
+	if got := receive(t, sub, 2); got[0] != 1 || got[1] != 2 {
+		t.Errorf("received %v, want [1 2]", got)
+	}
+	if dropped := b.Metrics().Dropped; dropped != 1 {
+		t.Errorf("dropped %d events, want 1", dropped)
+	}
+}
+
+func TestMemoryBrokerDisconnect(t *testing.T) {
+	b := NewMemoryBroker(Options{BufferSize: 2, Policy: Disconnect})
+	slow := b.Subscribe(context.Background(), "topic")
+	fast := b.Subscribe(context.Background(), "topic")
+	for i := 1; i <= 3; i++ {
+		b.Publish("topic", i)
+		receive(t, fast, 1)
+	}
+
+	if got := receive(t, slow, 2); got[0] != 1 || got[1] != 2 {
+		t.Errorf("received %v, want [1 2]", got)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("slow subscriber still subscribed")
+	}
+	metrics := b.Metrics()
+	if metrics.Disconnected != 1 || metrics.Subscribers != 1 {
+		t.Errorf("disconnected %d, %d subscribers, want 1 and 1", metrics.Disconnected, metrics.Subscribers)
+	}
+}
+
+func TestMemoryBrokerContextDone(t *testing.T) {
+	b := NewMemoryBroker(Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := b.Subscribe(ctx, "topic")
+	cancel()
+
+	select {
+	case _, ok := <-sub.Events():
+		if ok {
+			t.Error("received an event, want the channel closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed")
+	}
+	waitFor(t, func() bool { return b.Metrics().Subscribers == 0 })
+}
+
+// receive returns the next n events of sub decoded as ints.
+func receive(t *testing.T, sub *Subscription, n int) []int {
+	t.Helper()
+	events := make([]int, 0, n)
+	for len(events) < n {
+		select {
+		case msg, ok := <-sub.Events():
+			if !ok {
+				t.Fatalf("subscription closed after %v", events)
+			}
+			var event int
+			if err := msg.Decode(&event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want %d events", events, n)
+		}
+	}
+	return events
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type Metrics struct {
+	Topics       map[string]int `json:"topics"`
+	Subscribers  int            `json:"subscribers"`
+	Published    uint64         `json:"published"`
+	Dropped      uint64         `json:"dropped"`
+	Disconnected uint64         `json:"disconnected"`
+}
+
+// Metrics returns the subscriber count of every topic and the event counters.
+func (b *MemoryBroker) Metrics() Metrics {
+	metrics := Metrics{
+		Topics:       make(map[string]int),
+		Published:    atomic.LoadUint64(&b.published),
+		Dropped:      atomic.LoadUint64(&b.dropped),
+		Disconnected: atomic.LoadUint64(&b.disconnected),
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for topic, subs := range b.topics {
+		metrics.Topics[topic] = len(subs)
+		metrics.Subscribers += len(subs)
+	}
+	return metrics
+}
+
+// MetricsHandler serves the metrics of the broker as JSON. The handler is
+// public, so the subscribers are counted per event name and not per topic,
+// which would tell the usernames and posts being watched.
+func MetricsHandler(b Broker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics := b.Metrics()
+		metrics.Topics = countByName(metrics.Topics)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metrics)
+	})
+}
+
+// countByName sums the subscribers of the topics of a post or a user into
+// their event name.
+func countByName(topics map[string]int) map[string]int {
+	names := make(map[string]int, len(topics))
+	for topic, count := range topics {
+		if i := strings.IndexAny(topic, ":@"); i >= 0 {
+			topic = topic[:i]
+		}
+		names[topic] += count
+	}
+	return names
+}
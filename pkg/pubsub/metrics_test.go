@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsHandlerCountsByName(t *testing.T) {
+	b := NewMemoryBroker(Options{})
+	b.Subscribe(context.Background(), TopicNewPost)
+	b.Subscribe(context.Background(), UserTopic(TopicNotification, "alice"))
+	b.Subscribe(context.Background(), UserTopic(TopicNotification, "bob"))
+	b.Subscribe(context.Background(), PostTopic(TopicPostUpdated, "5fd1e2"))
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(b).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/pubsub", nil))
+
+	metrics := Metrics{}
+	if err := json.NewDecoder(rec.Body).Decode(&metrics); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{TopicNewPost: 1, TopicNotification: 2, TopicPostUpdated: 1}
+	if len(metrics.Topics) != len(want) {
+		t.Errorf("topics %v, want %v", metrics.Topics, want)
+	}
+	for name, count := range want {
+		if metrics.Topics[name] != count {
+			t.Errorf("topics %v, want %v", metrics.Topics, want)
+		}
+	}
+	if metrics.Subscribers != 4 {
+		t.Errorf("%d subscribers, want 4", metrics.Subscribers)
+	}
+}
@@ -0,0 +1,141 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+)
+
+const (
+	DefaultRedisPrefix = "social-network:"
+
+	reconnectDelay = time.Second
+)
+
+// RedisBroker publishes events through Redis Pub/Sub so subscribers connected
+// to any replica receive them. Every replica keeps a single Redis subscription
+// on all topics and fans the events out to its own subscribers.
+type RedisBroker struct {
+	local  *MemoryBroker
+	pool   *redis.Pool
+	prefix string
+	Logger *logger.AppLog
+
+	lock   sync.Mutex
+	conn   *redis.PubSubConn
+	closed bool
+	done   chan struct{}
+}
+
+// NewRedisBroker publishes through the Redis of pool, which the broker does
+// not close.
+func NewRedisBroker(pool *redis.Pool, prefix string, options Options, log *logger.AppLog) *RedisBroker {
+	b := &RedisBroker{
+		local:  NewMemoryBroker(options),
+		pool:   pool,
+		prefix: prefix,
+		Logger: log,
+		done:   make(chan struct{}),
+	}
+	go b.receive()
+	return b
+}
+
+// Publish sends the event to the subscribers of topic on every replica.
+func (b *RedisBroker) Publish(topic string, event interface{}) {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		b.Logger.Errorf("Encode event of %s error %#v", topic, err)
+		return
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PUBLISH", b.prefix+topic, msg); err != nil {
+		b.Logger.Errorf("Publish event of %s error %#v", topic, err)
+	}
+}
+
+func (b *RedisBroker) PublishLocal(topic string, event interface{}) {
+	b.local.Publish(topic, event)
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, topic string) *Subscription {
+	return b.local.Subscribe(ctx, topic)
+}
+
+func (b *RedisBroker) Metrics() Metrics {
+	return b.local.Metrics()
+}
+
+func (b *RedisBroker) Close() error {
+	b.lock.Lock()
+	b.closed = true
+	if b.conn != nil {
+		b.conn.PUnsubscribe()
+	}
+	b.lock.Unlock()
+
+	<-b.done
+	return nil
+}
+
+// receive dispatches the events of Redis to the local subscribers and
+// subscribes again whenever the connection is lost.
+func (b *RedisBroker) receive() {
+	defer close(b.done)
+	for {
+		if err := b.listen(); err != nil {
+			b.Logger.Errorf("Redis subscription error %#v", err)
+		}
+
+		b.lock.Lock()
+		closed := b.closed
+		b.lock.Unlock()
+		if closed {
+			return
+		}
+		time.Sleep(reconnectDelay)
+	}
+}
+
+func (b *RedisBroker) listen() error {
+	conn := &redis.PubSubConn{Conn: b.pool.Get()}
+	// Close may be writing to the connection, wait for it before closing
+	defer func() {
+		b.lock.Lock()
+		b.conn = nil
+		b.lock.Unlock()
+		conn.Close()
+	}()
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return nil
+	}
+	b.conn = conn
+	err := conn.PSubscribe(b.prefix + "*")
+	b.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		switch v := conn.Receive().(type) {
+		case redis.Message:
+			b.local.dispatch(strings.TrimPrefix(v.Channel, b.prefix), v.Data)
+		case redis.Subscription:
+			if v.Count == 0 {
+				return nil
+			}
+		case error:
+			return v
+		}
+	}
+}
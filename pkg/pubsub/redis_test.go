@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+)
+
+func newTestRedisPool(t *testing.T, m *miniredis.Miniredis) *redis.Pool {
+	t.Helper()
+	pool, err := internal.NewRedisPool("redis://" + m.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func newTestRedisBroker(pool *redis.Pool) *RedisBroker {
+	return NewRedisBroker(pool, DefaultRedisPrefix, Options{}, logger.NewAppLog())
+}
+
+func TestRedisBrokerDeliversToAllBrokers(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	firstPool := newTestRedisPool(t, m)
+	defer firstPool.Close()
+	first := newTestRedisBroker(firstPool)
+	defer first.Close()
+	secondPool := newTestRedisPool(t, m)
+	defer secondPool.Close()
+	second := newTestRedisBroker(secondPool)
+	defer second.Close()
+	waitFor(t, func() bool { return m.PubSubNumPat() == 2 })
+
+	firstSub := first.Subscribe(context.Background(), "topic")
+	secondSub := second.Subscribe(context.Background(), "topic")
+	other := second.Subscribe(context.Background(), "other")
+
+	first.Publish("topic", 1)
+	second.Publish("topic", 2)
+
+	for _, sub := range []*Subscription{firstSub, secondSub} {
+		if got := receive(t, sub, 2); got[0] != 1 || got[1] != 2 {
+			t.Errorf("received %v, want [1 2]", got)
+		}
+	}
+	select {
+	case msg := <-other.Events():
+		t.Errorf("subscriber of another topic received %s", msg)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestRedisBrokerPublishLocal(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	firstPool := newTestRedisPool(t, m)
+	defer firstPool.Close()
+	first := newTestRedisBroker(firstPool)
+	defer first.Close()
+	secondPool := newTestRedisPool(t, m)
+	defer secondPool.Close()
+	second := newTestRedisBroker(secondPool)
+	defer second.Close()
+	waitFor(t, func() bool { return m.PubSubNumPat() == 2 })
+
+	firstSub := first.Subscribe(context.Background(), "topic")
+	secondSub := second.Subscribe(context.Background(), "topic")
+	first.PublishLocal("topic", 1)
+
+	if got := receive(t, firstSub, 1); got[0] != 1 {
+		t.Errorf("received %v, want [1]", got)
+	}
+	select {
+	case msg := <-secondSub.Events():
+		t.Errorf("other broker received %s", msg)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestRedisBrokerResubscribesAfterReconnect(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	pool := newTestRedisPool(t, m)
+	defer pool.Close()
+	b := newTestRedisBroker(pool)
+	defer b.Close()
+	sub := b.Subscribe(context.Background(), "topic")
+	waitFor(t, func() bool { return m.PubSubNumPat() == 1 })
+
+	m.Close()
+	if err := m.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	// the broker subscribes again after reconnectDelay
+	deadline := time.Now().Add(reconnectDelay + 2*time.Second)
+	for m.PubSubNumPat() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("broker did not subscribe again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	b.Publish("topic", 1)
+	if got := receive(t, sub, 1); got[0] != 1 {
+		t.Errorf("received %v, want [1]", got)
+	}
+}
+
+func TestRedisBrokerClose(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	pool := newTestRedisPool(t, m)
+	defer pool.Close()
+	b := newTestRedisBroker(pool)
+	waitFor(t, func() bool { return m.PubSubNumPat() == 1 })
+
+	done := make(chan error)
+	go func() { done <- b.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+	waitFor(t, func() bool { return m.PubSubNumPat() == 0 })
+}
@@ -0,0 +1,23 @@
+package pubsub
+
+// Topics of post events, the topics of a single post are suffixed with its id.
+const (
+	TopicNewPost      = "newPost"
+	TopicPostUpdated  = "postUpdated"
+	TopicCommentAdded = "commentAdded"
+	TopicLikeChanged  = "likeChanged"
+	TopicPostDeleted  = "postDeleted"
+)
+
+// PostTopic returns the topic of an event of a single post.
+func PostTopic(name string, postID string) string {
+	return name + ":" + postID
+}
+
+// TopicNotification is suffixed with the username of the recipient.
+const TopicNotification = "notificationReceived"
+
+// UserTopic returns the topic of an event of a single user.
+func UserTopic(name string, username string) string {
+	return name + "@" + username
+}
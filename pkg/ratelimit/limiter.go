@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+)
+
+// Limiter limits how often a client calls the operations having a policy.
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+	Logger   *logger.AppLog
+}
+
+func NewLimiter(store Store, policies map[string]Policy, log *logger.AppLog) *Limiter {
+	return &Limiter{
+		store:    store,
+		policies: policies,
+		Logger:   log,
+	}
+}
+
+// Allow takes a token of the client for the operation and returns a rate
+// limited error when there is none left. The call is allowed when the store
+// fails, an unreachable Redis must not take the API down.
+func (l *Limiter) Allow(ctx context.Context, operation string, client string) error {
+	policy, ok := l.policies[operation]
+	if !ok {
+		return nil
+	}
+	allowed, retryAfter, err := l.store.Take(ctx, operation+":"+client, policy)
+	if err != nil {
+		l.Logger.Errorf("Rate limit error %#v", err)
+		return nil
+	}
+	if !allowed {
+		return apperrors.RateLimited(retryAfter)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, policy Policy) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestLimiterAllow(t *testing.T) {
+	s, _ := newTestMemoryStore()
+	l := NewLimiter(s, map[string]Policy{"login": {Burst: 1, Period: time.Minute}}, logger.NewAppLog())
+
+	if err := l.Allow(context.Background(), "login", "ip:10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	err := l.Allow(context.Background(), "login", "ip:10.0.0.1")
+	if apperrors.CodeOf(err) != apperrors.CodeRateLimited {
+		t.Errorf("error %v, want rate limited", err)
+	}
+	if err := l.Allow(context.Background(), "login", "ip:10.0.0.2"); err != nil {
+		t.Errorf("other client limited: %v", err)
+	}
+	// operations without a policy are not limited
+	for i := 0; i < 3; i++ {
+		if err := l.Allow(context.Background(), "logout", "ip:10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterFailsOpen(t *testing.T) {
+	l := NewLimiter(failingStore{}, map[string]Policy{"login": {Burst: 1, Period: time.Minute}}, logger.NewAppLog())
+	if err := l.Allow(context.Background(), "login", "ip:10.0.0.1"); err != nil {
+		t.Errorf("error %v, want the call allowed", err)
+	}
+}
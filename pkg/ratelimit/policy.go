@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is a token bucket holding up to Burst tokens and refilled with
+// Burst tokens every Period. Each call takes a token.
+type Policy struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Rate is the number of tokens added per second.
+func (p Policy) Rate() float64 {
+	return float64(p.Burst) / p.Period.Seconds()
+}
+
+// ParsePolicies reads policies written as operation=count/period separated
+// by commas, e.g. "createPost=10/1m,login=5/1m".
+func ParsePolicies(s string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid rate limit %q", entry)
+		}
+		limit := strings.SplitN(parts[1], "/", 2)
+		if len(limit) != 2 {
+			return nil, fmt.Errorf("Invalid rate limit %q", entry)
+		}
+		burst, err := strconv.Atoi(limit[0])
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("Invalid count of rate limit %q", entry)
+		}
+		period, err := time.ParseDuration(limit[1])
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("Invalid period of rate limit %q", entry)
+		}
+		policies[strings.TrimSpace(parts[0])] = Policy{Burst: burst, Period: period}
+	}
+	return policies, nil
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/config"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(" createPost=10/1m, login=5/30s,,register=1/1h ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Policy{
+		"createPost": {Burst: 10, Period: time.Minute},
+		"login":      {Burst: 5, Period: 30 * time.Second},
+		"register":   {Burst: 1, Period: time.Hour},
+	}
+	if len(policies) != len(want) {
+		t.Errorf("policies %v, want %v", policies, want)
+	}
+	for operation, policy := range want {
+		if policies[operation] != policy {
+			t.Errorf("policy of %s %v, want %v", operation, policies[operation], policy)
+		}
+	}
+}
+
+func TestParsePoliciesInvalid(t *testing.T) {
+	for _, s := range []string{"login", "login=5", "login=x/1m", "login=0/1m", "login=5/1", "login=5/-1m"} {
+		if _, err := ParsePolicies(s); err == nil {
+			t.Errorf("parsed %q", s)
+		}
+	}
+}
+
+func TestPolicyRate(t *testing.T) {
+	if rate := (Policy{Burst: 60, Period: time.Minute}).Rate(); rate != 1 {
+		t.Errorf("rate %v, want 1", rate)
+	}
+}
+
+func TestParseDefaultPolicies(t *testing.T) {
+	policies, err := ParsePolicies(config.DefaultRateLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// liking and unliking in turn must not escape the limit
+	if policies["unlikePost"] != policies["likePost"] {
+		t.Errorf("unlikePost policy %v, want the likePost one %v", policies["unlikePost"], policies["likePost"])
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const DefaultRedisPrefix = "social-network:ratelimit:"
+
+// takeScript refills and takes from a bucket atomically, with the clock of
+// Redis so the replicas agree on the time. It returns whether a token was
+// taken and otherwise the milliseconds until the next one.
+var takeScript = redis.NewScript(1, `
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+tokens = math.min(burst, tokens + (now - updated) / 1000 * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`)
+
+// RedisStore shares the buckets between the replicas.
+type RedisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore keeps the buckets in the Redis of pool, which the store does
+// not close.
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{pool: pool, prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy) (bool, time.Duration, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer conn.Close()
+
+	result, err := redis.Int64s(takeScript.Do(conn, s.prefix+key,
+		policy.Burst, strconv.FormatFloat(policy.Rate(), 'f', -1, 64)))
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
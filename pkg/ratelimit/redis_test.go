@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/trinhdaiphuc/social-network/internal"
+)
+
+// newTestRedisStore runs the script on a miniredis whose clock is set by the
+// tests through the returned function.
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis, func(time.Duration)) {
+	t.Helper()
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.SetTime(now)
+	pool, err := internal.NewRedisPool("redis://" + m.Addr())
+	if err != nil {
+		m.Close()
+		t.Fatal(err)
+	}
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		m.SetTime(now)
+		m.FastForward(d)
+	}
+	return NewRedisStore(pool, DefaultRedisPrefix), m, advance
+}
+
+func TestRedisStoreExhaustsBucket(t *testing.T) {
+	s, m, _ := newTestRedisStore(t)
+	defer m.Close()
+	defer s.pool.Close()
+	policy := Policy{Burst: 3, Period: 3 * time.Second}
+
+	for i := 0; i < policy.Burst; i++ {
+		if allowed, _ := take(t, s, "key", policy); !allowed {
+			t.Fatalf("call %d denied, want the burst allowed", i+1)
+		}
+	}
+	allowed, wait := take(t, s, "key", policy)
+	if allowed {
+		t.Fatal("call allowed with an empty bucket")
+	}
+	if wait != time.Second {
+		t.Errorf("wait %s, want 1s", wait)
+	}
+	// other keys have their own bucket
+	if allowed, _ := take(t, s, "other", policy); !allowed {
+		t.Error("other key denied")
+	}
+	if !m.Exists(DefaultRedisPrefix + "key") {
+		t.Error("bucket not stored under the prefix")
+	}
+}
+
+func TestRedisStoreRefills(t *testing.T) {
+	s, m, advance := newTestRedisStore(t)
+	defer m.Close()
+	defer s.pool.Close()
+	policy := Policy{Burst: 2, Period: 2 * time.Second}
+
+	take(t, s, "key", policy)
+	take(t, s, "key", policy)
+	advance(400 * time.Millisecond)
+	allowed, wait := take(t, s, "key", policy)
+	if allowed {
+		t.Fatal("call allowed before a token was added")
+	}
+	if wait != 600*time.Millisecond {
+		t.Errorf("wait %s, want 600ms", wait)
+	}
+
+	advance(wait)
+	if allowed, _ := take(t, s, "key", policy); !allowed {
+		t.Error("call denied after the wait")
+	}
+	if allowed, _ := take(t, s, "key", policy); allowed {
+		t.Error("call allowed with an empty bucket")
+	}
+
+	// a bucket never holds more than the burst
+	advance(time.Hour)
+	for i := 0; i < policy.Burst; i++ {
+		if allowed, _ := take(t, s, "key", policy); !allowed {
+			t.Fatalf("call %d denied after a refill", i+1)
+		}
+	}
+	if allowed, _ := take(t, s, "key", policy); allowed {
+		t.Error("bucket refilled over the burst")
+	}
+}
+
+func TestRedisStoreExpiresFullBuckets(t *testing.T) {
+	s, m, advance := newTestRedisStore(t)
+	defer m.Close()
+	defer s.pool.Close()
+	policy := Policy{Burst: 5, Period: 10 * time.Second}
+
+	take(t, s, "key", policy)
+	if ttl := m.TTL(DefaultRedisPrefix + "key"); ttl != policy.Period {
+		t.Errorf("TTL %s, want the time to refill %s", ttl, policy.Period)
+	}
+	advance(policy.Period)
+	if m.Exists(DefaultRedisPrefix + "key") {
+		t.Error("full bucket kept")
+	}
+}
+
+func TestRedisStoreShared(t *testing.T) {
+	s, m, _ := newTestRedisStore(t)
+	defer m.Close()
+	defer s.pool.Close()
+	pool, err := internal.NewRedisPool("redis://" + m.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	// another replica takes from the same bucket
+	other := NewRedisStore(pool, DefaultRedisPrefix)
+	policy := Policy{Burst: 2, Period: time.Minute}
+
+	take(t, s, "key", policy)
+	take(t, other, "key", policy)
+	if allowed, _ := take(t, s, "key", policy); allowed {
+		t.Error("replicas have their own bucket")
+	}
+}
+
+func TestRedisStoreError(t *testing.T) {
+	s, m, _ := newTestRedisStore(t)
+	defer s.pool.Close()
+	m.Close()
+	if _, _, err := s.Take(context.Background(), "key", Policy{Burst: 1, Period: time.Second}); err == nil {
+		t.Error("took a token without Redis")
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the buckets of the limited keys.
+type Store interface {
+	// Take takes a token from the bucket of the key. When the bucket is
+	// empty it returns false and how long until a token is added.
+	Take(ctx context.Context, key string, policy Policy) (bool, time.Duration, error)
+}
+
+// sweepInterval is how often the memory store forgets the full buckets.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	policy  Policy
+}
+
+// fill adds the tokens earned since the last update.
+func (b *bucket) fill(now time.Time) {
+	b.tokens += now.Sub(b.updated).Seconds() * b.policy.Rate()
+	if b.tokens > float64(b.policy.Burst) {
+		b.tokens = float64(b.policy.Burst)
+	}
+	b.updated = now
+}
+
+// MemoryStore keeps the buckets of this replica only.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.policy != policy {
+		b = &bucket{tokens: float64(policy.Burst), updated: now, policy: policy}
+		s.buckets[key] = b
+	}
+	b.fill(now)
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / policy.Rate() * float64(time.Second))
+		return false, wait, nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// sweep forgets the buckets that are full again, a new bucket is full.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.fill(now)
+		if b.tokens >= float64(b.policy.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
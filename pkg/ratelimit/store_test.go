@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is moved forward by the tests instead of waiting.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMemoryStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	s.lastSweep = clock.now
+	return s, clock
+}
+
+func take(t *testing.T, s Store, key string, policy Policy) (bool, time.Duration) {
+	t.Helper()
+	allowed, wait, err := s.Take(context.Background(), key, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return allowed, wait
+}
+
+func TestMemoryStoreExhaustsBucket(t *testing.T) {
+	s, _ := newTestMemoryStore()
+	policy := Policy{Burst: 3, Period: 3 * time.Second}
+
+	for i := 0; i < policy.Burst; i++ {
+		if allowed, _ := take(t, s, "key", policy); !allowed {
+			t.Fatalf("call %d denied, want the burst allowed", i+1)
+		}
+	}
+	allowed, wait := take(t, s, "key", policy)
+	if allowed {
+		t.Fatal("call allowed with an empty bucket")
+	}
+	if wait != time.Second {
+		t.Errorf("wait %s, want 1s", wait)
+	}
+	// other keys have their own bucket
+	if allowed, _ := take(t, s, "other", policy); !allowed {
+		t.Error("other key denied")
+	}
+}
+
+func TestMemoryStoreRefillsBucket(t *testing.T) {
+	s, clock := newTestMemoryStore()
+	policy := Policy{Burst: 2, Period: 2 * time.Second}
+	take(t, s, "key", policy)
+	take(t, s, "key", policy)
+
+	clock.Add(500 * time.Millisecond)
+	allowed, wait := take(t, s, "key", policy)
+	if allowed || wait != 500*time.Millisecond {
+		t.Errorf("allowed %v, wait %s, want denied for 500ms", allowed, wait)
+	}
+
+	clock.Add(500 * time.Millisecond)
+	if allowed, _ := take(t, s, "key", policy); !allowed {
+		t.Error("denied after a token was added")
+	}
+	if allowed, _ := take(t, s, "key", policy); allowed {
+		t.Error("allowed with an empty bucket")
+	}
+
+	// a bucket never holds more than the burst
+	clock.Add(time.Hour)
+	for i := 0; i < policy.Burst; i++ {
+		if allowed, _ := take(t, s, "key", policy); !allowed {
+			t.Fatalf("call %d denied after refill", i+1)
+		}
+	}
+	if allowed, _ := take(t, s, "key", policy); allowed {
+		t.Error("allowed more than the burst after a long wait")
+	}
+}
+
+func TestMemoryStoreNewPolicy(t *testing.T) {
+	s, _ := newTestMemoryStore()
+	take(t, s, "key", Policy{Burst: 1, Period: time.Minute})
+	if allowed, _ := take(t, s, "key", Policy{Burst: 5, Period: time.Minute}); !allowed {
+		t.Error("denied with a new policy, want a new bucket")
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	s, clock := newTestMemoryStore()
+	take(t, s, "full", Policy{Burst: 1, Period: time.Second})
+	take(t, s, "slow", Policy{Burst: 1, Period: time.Hour})
+
+	clock.Add(sweepInterval + time.Second)
+	take(t, s, "new", Policy{Burst: 2, Period: time.Hour})
+
+	if _, ok := s.buckets["full"]; ok {
+		t.Error("refilled bucket kept")
+	}
+	if _, ok := s.buckets["slow"]; !ok {
+		t.Error("bucket still refilling forgotten")
+	}
+	if allowed, _ := take(t, s, "slow", Policy{Burst: 1, Period: time.Hour}); allowed {
+		t.Error("allowed from a bucket still refilling")
+	}
+}
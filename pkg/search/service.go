@@ -0,0 +1,192 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/comment"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/post"
+	"github.com/trinhdaiphuc/social-network/pkg/user"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxResults bounds how deep offset pagination may go into the ranking.
+	MaxResults = 500
+	// MaxQueryLength bounds the length of a search query.
+	MaxQueryLength = 200
+	// UserLimit is the number of users returned by SearchUsers.
+	UserLimit = 20
+)
+
+type SearchService interface {
+	SearchPosts(ctx context.Context, query string, first *int, after *string) (*models.PostSearchConnection, error)
+	SearchUsers(ctx context.Context, query string) ([]*models.User, error)
+}
+
+type service struct {
+	Logger *logger.AppLog
+}
+
+// NewSearchService needs the post, comment and user repositories to be
+// created first since it searches their collections.
+func NewSearchService(log *logger.AppLog) SearchService {
+	return &service{Logger: log}
+}
+
+type hit struct {
+	post  *models.Post
+	score float64
+	text  string
+}
+
+// SearchPosts ranks posts by the relevance of their body or of their most
+// relevant comment. A post matched through a comment gets its snippet from
+// that comment.
+func (s *service) SearchPosts(ctx context.Context, query string, first *int, after *string) (*models.PostSearchConnection, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := internal.DefaultPageSize
+	if first != nil {
+		if *first < 0 {
+			return nil, apperrors.Validation("Page size must not be negative")
+		}
+		limit = *first
+		if limit > internal.MaxPageSize {
+			limit = internal.MaxPageSize
+		}
+	}
+	start := 0
+	if after != nil {
+		offset, err := internal.DecodeOffsetCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		start = offset + 1
+	}
+	if start >= MaxResults {
+		return &models.PostSearchConnection{
+			Edges:    []*models.PostSearchEdge{},
+			PageInfo: &models.PageInfo{HasPreviousPage: true},
+		}, nil
+	}
+	if start+limit > MaxResults {
+		limit = MaxResults - start
+	}
+
+	// Fetch one more than the page from each collection to know whether
+	// another page exists after merging.
+	want := start + limit + 1
+	posts, postScores, err := post.GetPostRepository().Search(ctx, query, want)
+	if err != nil {
+		s.Logger.Errorf("Search posts error %#v", err)
+		return nil, err
+	}
+	comments, commentScores, err := comment.GetCommentRepository().Search(ctx, query, want)
+	if err != nil {
+		s.Logger.Errorf("Search comments error %#v", err)
+		return nil, err
+	}
+
+	hits := map[primitive.ObjectID]*hit{}
+	for i, p := range posts {
+		hits[p.ID] = &hit{post: p, score: postScores[i], text: p.Body}
+	}
+	missing := []primitive.ObjectID{}
+	for i, c := range comments {
+		h, ok := hits[c.PostID]
+		if !ok {
+			h = &hit{}
+			hits[c.PostID] = h
+			missing = append(missing, c.PostID)
+		}
+		if commentScores[i] > h.score {
+			h.score = commentScores[i]
+			h.text = c.Body
+		}
+	}
+
+	found, err := post.GetPostRepository().GetListByIDs(ctx, missing)
+	if err != nil {
+		s.Logger.Errorf("Search posts error %#v", err)
+		return nil, err
+	}
+	for _, p := range found {
+		hits[p.ID].post = p
+	}
+
+	ranked := make([]*hit, 0, len(hits))
+	for _, h := range hits {
+		// comments of a deleted post may outlive it
+		if h.post != nil {
+			ranked = append(ranked, h)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].post.ID.Hex() > ranked[j].post.ID.Hex()
+	})
+
+	end := start + limit
+	hasMore := len(ranked) > end
+	if end > len(ranked) {
+		end = len(ranked)
+	}
+	if start > end {
+		start = end
+	}
+
+	edges := make([]*models.PostSearchEdge, 0, end-start)
+	cursors := make([]string, 0, end-start)
+	for i, h := range ranked[start:end] {
+		cursor := internal.EncodeOffsetCursor(start + i)
+		edges = append(edges, &models.PostSearchEdge{
+			Cursor:  cursor,
+			Node:    h.post,
+			Score:   h.score,
+			Snippet: Highlight(h.text, query),
+		})
+		cursors = append(cursors, cursor)
+	}
+
+	info := &models.PageInfo{HasNextPage: hasMore && end < MaxResults, HasPreviousPage: start > 0}
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+	return &models.PostSearchConnection{Edges: edges, PageInfo: info}, nil
+}
+
+func (s *service) SearchUsers(ctx context.Context, query string) ([]*models.User, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	users, err := user.GetUserRepository().Search(ctx, query, UserLimit)
+	if err != nil {
+		s.Logger.Errorf("Search users error %#v", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+func normalizeQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", apperrors.Validation("Search query must not be empty")
+	}
+	if len(query) > MaxQueryLength {
+		return "", apperrors.Validation("Search query must not be longer than %d characters", MaxQueryLength)
+	}
+	return query, nil
+}
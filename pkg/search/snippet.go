@@ -0,0 +1,135 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	snippetLength = 160
+	ellipsis      = "…"
+)
+
+type span struct {
+	start, end int
+	match      bool
+}
+
+// Highlight returns a window of text around the first word matching the
+// query, with every matching word wrapped in <mark>. The text is HTML escaped
+// so the snippet can be rendered as markup.
+func Highlight(text, query string) string {
+	terms := queryTerms(query)
+	runes := []rune(text)
+	words := splitWords(runes, terms)
+
+	start, end := 0, len(runes)
+	if len(runes) > snippetLength {
+		for _, w := range words {
+			if w.match {
+				// keep some context before the first match
+				start = w.start - snippetLength/4
+				break
+			}
+		}
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippetLength
+		if end > len(runes) {
+			end = len(runes)
+			start = end - snippetLength
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	pos := start
+	for _, w := range words {
+		if w.end <= start || w.start >= end {
+			continue
+		}
+		wStart, wEnd := w.start, w.end
+		if wStart < start {
+			wStart = start
+		}
+		if wEnd > end {
+			wEnd = end
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:wStart])))
+		word := html.EscapeString(string(runes[wStart:wEnd]))
+		if w.match {
+			b.WriteString("<mark>" + word + "</mark>")
+		} else {
+			b.WriteString(word)
+		}
+		pos = wEnd
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString(ellipsis)
+	}
+	return b.String()
+}
+
+// queryTerms returns the lower cased words of a MongoDB text query, leaving
+// out negated terms and phrases which never appear in a match.
+func queryTerms(query string) []string {
+	terms := []string{}
+	negatedPhrase := false
+	for _, token := range strings.Fields(query) {
+		if negatedPhrase {
+			negatedPhrase = !strings.HasSuffix(token, `"`)
+			continue
+		}
+		if strings.HasPrefix(token, "-") {
+			// a negated phrase goes on until its closing quote
+			negatedPhrase = strings.HasPrefix(token, `-"`) && (token == `-"` || !strings.HasSuffix(token, `"`))
+			continue
+		}
+		for _, term := range strings.FieldsFunc(strings.ToLower(token), isSeparator) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// splitWords returns the words of text and whether each one matches a term.
+// MongoDB stems the words it indexes, so a word matches a term when either
+// is a prefix of the other.
+func splitWords(runes []rune, terms []string) []span {
+	words := []span{}
+	for i := 0; i < len(runes); {
+		if isSeparator(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && !isSeparator(runes[j]) {
+			j++
+		}
+		word := strings.ToLower(string(runes[i:j]))
+		words = append(words, span{start: i, end: j, match: matches(word, terms)})
+		i = j
+	}
+	return words
+}
+
+func matches(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+		if len([]rune(word)) >= 3 && strings.HasPrefix(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Golang", []string{"golang"}},
+		{"go  rust", []string{"go", "rust"}},
+		{`"exact phrase"`, []string{"exact", "phrase"}},
+		{"go -java", []string{"go"}},
+		{`go -"java beans" rust`, []string{"go", "rust"}},
+		{`go -"java"`, []string{"go"}},
+		{`-" java " go`, []string{"go"}},
+		{"c++ node.js", []string{"c", "node", "js"}},
+		{"-", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := queryTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terms of %q = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"match", "I love golang", "golang", "I love <mark>golang</mark>"},
+		{"case folded", "Go is GREAT", "great", "Go is <mark>GREAT</mark>"},
+		{"every match", "go go went", "go", "<mark>go</mark> <mark>go</mark> went"},
+		// MongoDB matches the stems of the words
+		{"stemmed word", "running fast", "run", "<mark>running</mark> fast"},
+		{"stemmed term", "the run was long", "running", "the <mark>run</mark> was long"},
+		{"short word", "a go at it", "gone", "a go at it"},
+		{"negated term", "java and go", "go -java", "java and <mark>go</mark>"},
+		{"negated phrase", "java beans and go", `go -"java beans"`, "java beans and <mark>go</mark>"},
+		{"no match", "nothing here", "golang", "nothing here"},
+		{"unicode", "Ich mag Käse", "käse", "Ich mag <mark>Käse</mark>"},
+		{"escaped markup", `<script>alert("x")</script>`, "alert",
+			"&lt;script&gt;<mark>alert</mark>(&#34;x&#34;)&lt;/script&gt;"},
+		{"escaped match", "<b>bold</b>", "b", "&lt;<mark>b</mark>&gt;<mark>bold</mark>&lt;/<mark>b</mark>&gt;"},
+		{"escaped quote", `it's "quoted" & done`, "quoted", "it&#39;s &#34;<mark>quoted</mark>&#34; &amp; done"},
+		{"query is not markup", "a <mark> tag", "<mark>", "a &lt;<mark>mark</mark>&gt; tag"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.query); got != tt.want {
+			t.Errorf("%s: Highlight(%q, %q) = %q, want %q", tt.name, tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+// words returns the text of a long post made of n words.
+func words(n int) string {
+	ws := make([]string, n)
+	for i := range ws {
+		ws[i] = "word"
+	}
+	return strings.Join(ws, " ")
+}
+
+func TestHighlightWindow(t *testing.T) {
+	long := words(100)
+	tests := []struct {
+		name   string
+		text   string
+		prefix string
+		suffix string
+	}{
+		{"match at the start", "golang " + long, "<mark>golang</mark> word", ellipsis},
+		{"match in the middle", long + " golang " + long, ellipsis, ellipsis},
+		{"match at the end", long + " golang", ellipsis, "<mark>golang</mark>"},
+		{"no match", long, "word word", ellipsis},
+	}
+	for _, tt := range tests {
+		got := Highlight(tt.text, "golang")
+		if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) {
+			t.Errorf("%s: snippet %q does not start with %q and end with %q", tt.name, got, tt.prefix, tt.suffix)
+		}
+		if strings.Contains(tt.text, "golang") && !strings.Contains(got, "<mark>golang</mark>") {
+			t.Errorf("%s: snippet %q misses the match", tt.name, got)
+		}
+		text := strings.NewReplacer("<mark>", "", "</mark>", "", ellipsis, "").Replace(got)
+		if n := utf8.RuneCountInString(text); n != snippetLength {
+			t.Errorf("%s: snippet of %d characters, want %d", tt.name, n, snippetLength)
+		}
+	}
+}
+
+func TestHighlightWindowKeepsContext(t *testing.T) {
+	text := words(100) + " golang " + words(100)
+	got := Highlight(text, "golang")
+	before := strings.TrimPrefix(got[:strings.Index(got, "<mark>")], ellipsis)
+	if n := utf8.RuneCountInString(before); n != snippetLength/4 {
+		t.Errorf("%d characters before the match, want %d", n, snippetLength/4)
+	}
+}
+
+func TestHighlightWindowEscapesCutText(t *testing.T) {
+	// the window never splits an escaped character
+	text := strings.Repeat("<&> ", 60) + "golang " + strings.Repeat("<&> ", 60)
+	got := Highlight(text, "golang")
+	stripped := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(got)
+	if strings.ContainsAny(stripped, "<>") {
+		t.Errorf("snippet %q is not escaped", got)
+	}
+	for _, s := range strings.Split(stripped, "&")[1:] {
+		if !strings.HasPrefix(s, "lt;") && !strings.HasPrefix(s, "gt;") && !strings.HasPrefix(s, "amp;") {
+			t.Errorf("snippet %q has a broken entity", got)
+			break
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "sessions"
+)
+
+var (
+	sessionRepo *repository
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) (*models.Session, error)
+	Rotate(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*models.Session, error)
+	GetByUsedTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	IsActive(ctx context.Context, id primitive.ObjectID) (bool, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	RevokeByUserID(ctx context.Context, userID primitive.ObjectID) (int, error)
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewSessionRepository(db *mongo.Database, log *logger.AppLog) SessionRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"tokenHash": 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.M{
+				"usedTokenHashes": 1,
+			},
+		},
+		{
+			Keys: bson.M{
+				"userId": 1,
+			},
+		},
+		{
+			Keys: bson.M{
+				"expiresAt": 1,
+			},
+			// remove sessions as soon as their refresh token expires
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	ctx := context.Background()
+	sessionCollection := db.Collection(collectionName)
+	sessionCollection.Indexes().CreateMany(ctx, mod)
+	sessionRepo = &repository{
+		Collection: sessionCollection,
+		Logger:     log,
+	}
+	return sessionRepo
+}
+
+func GetSessionRepository() SessionRepository {
+	return sessionRepo
+}
+
+func (r *repository) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	result, err := r.Collection.InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return session, nil
+}
+
+// Rotate swaps the refresh token of an active session in a single update so
+// the same refresh token can never be exchanged twice.
+func (r *repository) Rotate(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*models.Session, error) {
+	filter := bson.M{
+		"tokenHash": tokenHash,
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{
+		"$set":  bson.M{"tokenHash": newTokenHash, "expiresAt": expiresAt},
+		"$push": bson.M{"usedTokenHashes": tokenHash},
+	}
+	session := &models.Session{}
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found session")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *repository) GetByUsedTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	session := &models.Session{}
+	if err := r.Collection.FindOne(ctx, bson.M{"usedTokenHashes": tokenHash}).Decode(session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, apperrors.NotFound("Not found session")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *repository) IsActive(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":       id,
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	count, err := r.Collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *repository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revokedAt": nil}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	_, err := r.Collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *repository) RevokeByUserID(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	filter := bson.M{"userId": userID, "revokedAt": nil}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	result, err := r.Collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionService interface {
+	Start(ctx context.Context, user *models.User) error
+	Rotate(ctx context.Context, refreshToken string) (*models.Session, string, error)
+	AccessToken(user *models.User, sessionID primitive.ObjectID) (string, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	RevokeAll(ctx context.Context, userID primitive.ObjectID) (int, error)
+}
+
+type service struct {
+	repository SessionRepository
+	Logger     *logger.AppLog
+}
+
+func NewSessionService(db *mongo.Database, log *logger.AppLog) SessionService {
+	r := NewSessionRepository(db, log)
+	return &service{repository: r, Logger: log}
+}
+
+// Start opens a new session for the user and sets its access and refresh tokens.
+func (s *service) Start(ctx context.Context, user *models.User) error {
+	refreshToken, err := internal.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	session, err := s.repository.Create(ctx, &models.Session{
+		UserID:    user.ID,
+		Username:  user.Username,
+		TokenHash: internal.HashToken(refreshToken),
+		ExpiresAt: refreshExpiry(),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	user.Token, err = s.AccessToken(user, session.ID)
+	if err != nil {
+		return err
+	}
+	user.RefreshToken = refreshToken
+	return nil
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a refresh token
+// that was already exchanged means it leaked, so its session is revoked.
+func (s *service) Rotate(ctx context.Context, refreshToken string) (*models.Session, string, error) {
+	hash := internal.HashToken(refreshToken)
+	newRefreshToken, err := internal.NewRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.repository.Rotate(ctx, hash, internal.HashToken(newRefreshToken), refreshExpiry())
+	if err != nil {
+		if reused, _ := s.repository.GetByUsedTokenHash(ctx, hash); reused != nil {
+			s.Logger.Warnf("Refresh token reused, revoke session %s of %s", reused.ID.Hex(), reused.Username)
+			if err := s.repository.Revoke(ctx, reused.ID); err != nil {
+				s.Logger.Errorf("Revoke session error %#v", err)
+			}
+		}
+		return nil, "", apperrors.Unauthenticated("Invalid refresh token")
+	}
+	return session, newRefreshToken, nil
+}
+
+func (s *service) AccessToken(user *models.User, sessionID primitive.ObjectID) (string, error) {
+	return internal.CreateTokenWithUser(config.GetConfig().JwtKey, user, sessionID.Hex(), config.GetConfig().AccessTokenExpiry)
+}
+
+func (s *service) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	return s.repository.Revoke(ctx, id)
+}
+
+func (s *service) RevokeAll(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	return s.repository.RevokeByUserID(ctx, userID)
+}
+
+func refreshExpiry() time.Time {
+	return time.Now().Add(time.Duration(config.GetConfig().RefreshTokenExpiry) * time.Minute)
+}
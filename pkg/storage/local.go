@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalPrefix is the path local files are served under.
+const LocalPrefix = "/uploads/"
+
+// LocalStorage keeps files in a directory and serves them over http.
+type LocalStorage struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalStorage stores files under root. baseURL is where the Handler is
+// reachable by clients, LocalPrefix when empty.
+func NewLocalStorage(root string, baseURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = LocalPrefix
+	}
+	return &LocalStorage{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed upload leaves nothing behind
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, io.LimitReader(content, size+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("Expected %d bytes but got %d", size, written)
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// Handler serves the stored files under LocalPrefix. Directories are not
+// listed.
+func (s *LocalStorage) Handler() http.Handler {
+	files := http.FileServer(http.Dir(s.root))
+	return http.StripPrefix(strings.TrimSuffix(LocalPrefix, "/"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	}))
+}
+
+// path maps a key to a file under the root, rejecting keys escaping it.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("Invalid key %s", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
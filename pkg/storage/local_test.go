@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocalStorage(t *testing.T) (*LocalStorage, string) {
+	t.Helper()
+	root, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewLocalStorage(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+func TestLocalStoragePutServeDelete(t *testing.T) {
+	s, root := newTestLocalStorage(t)
+	defer os.RemoveAll(root)
+	key := "attachments/a.pdf"
+	if err := s.Put(context.Background(), key, strings.NewReader("%PDF-1.4"), 8, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(root, "attachments", "a.pdf")); err != nil || string(data) != "%PDF-1.4" {
+		t.Errorf("stored %q, %v", data, err)
+	}
+	if url := s.URL(key); url != "/uploads/attachments/a.pdf" {
+		t.Errorf("URL = %s, want /uploads/attachments/a.pdf", url)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/uploads/"+key, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "%PDF-1.4" {
+		t.Errorf("served %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("served without nosniff")
+	}
+
+	if err := s.Delete(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(context.Background(), key); err != nil {
+		t.Errorf("delete of a missing file: %v", err)
+	}
+}
+
+func TestLocalStoragePutWrongSize(t *testing.T) {
+	s, root := newTestLocalStorage(t)
+	defer os.RemoveAll(root)
+	for _, size := range []int64{4, 16} {
+		if err := s.Put(context.Background(), "a.pdf", strings.NewReader("%PDF-1.4"), size, "application/pdf"); err == nil {
+			t.Errorf("stored 8 bytes as %d", size)
+		}
+	}
+	files, _ := ioutil.ReadDir(root)
+	if len(files) != 0 {
+		t.Errorf("left %d files behind", len(files))
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	s, root := newTestLocalStorage(t)
+	defer os.RemoveAll(root)
+	for _, key := range []string{"../a.pdf", "a/../../a.pdf", "", "/a.pdf", "a//b.pdf"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("data"), 4, "application/pdf"); err == nil {
+			t.Errorf("stored key %q", key)
+		}
+	}
+}
+
+func TestLocalStorageDoesNotListDirectories(t *testing.T) {
+	s, root := newTestLocalStorage(t)
+	defer os.RemoveAll(root)
+	if err := s.Put(context.Background(), "attachments/a.pdf", strings.NewReader("data"), 4, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/uploads/attachments/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("listed a directory with status %d", rec.Code)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Options configures an S3 compatible bucket. Endpoint is only needed for
+// other services than AWS such as MinIO, which also need ForcePathStyle.
+// Without an access key the default AWS credential chain is used.
+type S3Options struct {
+	Endpoint       string
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	ForcePathStyle bool
+	// BaseURL serves the files instead of the bucket, e.g. a CDN
+	BaseURL string
+}
+
+// S3Storage keeps files in an S3 compatible bucket.
+type S3Storage struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	baseURL  string
+}
+
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not set")
+	}
+	cfg := &aws.Config{
+		Region:           aws.String(opts.Region),
+		S3ForcePathStyle: aws.Bool(opts.ForcePathStyle),
+	}
+	if opts.Endpoint != "" {
+		cfg.Endpoint = aws.String(opts.Endpoint)
+	}
+	if opts.AccessKey != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(opts.AccessKey, opts.SecretKey, "")
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := opts.BaseURL
+	switch {
+	case baseURL != "":
+	case opts.Endpoint != "" && opts.ForcePathStyle:
+		baseURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + opts.Bucket
+	case opts.Endpoint != "":
+		scheme, host := "https://", opts.Endpoint
+		if i := strings.Index(host, "://"); i >= 0 {
+			scheme, host = host[:i+3], host[i+3:]
+		}
+		baseURL = scheme + opts.Bucket + "." + strings.TrimSuffix(host, "/")
+	default:
+		baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", opts.Bucket, opts.Region)
+	}
+
+	return &S3Storage{
+		client:   s3.New(sess),
+		uploader: s3manager.NewUploader(sess),
+		bucket:   opts.Bucket,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        io.LimitReader(content, size),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 answers the path style object requests of MinIO from memory.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func newFakeS3() (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	return fake, httptest.NewServer(fake)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = fakeObject{data: data, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) object(path string) (fakeObject, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	object, ok := f.objects[path]
+	return object, ok
+}
+
+func newTestS3Storage(t *testing.T, endpoint string) *S3Storage {
+	t.Helper()
+	s, err := NewS3Storage(S3Options{
+		Endpoint:       endpoint,
+		Region:         "us-east-1",
+		Bucket:         "uploads",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		ForcePathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3StoragePutDelete(t *testing.T) {
+	fake, server := newFakeS3()
+	defer server.Close()
+	s := newTestS3Storage(t, server.URL)
+
+	content := []byte("%PDF-1.4 document")
+	key := "attachments/5fd1e2.pdf"
+	if err := s.Put(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	object, ok := fake.object("/uploads/" + key)
+	if !ok {
+		t.Fatal("object not stored")
+	}
+	if !bytes.Equal(object.data, content) || object.contentType != "application/pdf" {
+		t.Errorf("stored %q as %s, want %q as application/pdf", object.data, object.contentType, content)
+	}
+
+	if err := s.Delete(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.object("/uploads/" + key); ok {
+		t.Error("object not deleted")
+	}
+}
+
+func TestS3StoragePutStopsAtSize(t *testing.T) {
+	fake, server := newFakeS3()
+	defer server.Close()
+	s := newTestS3Storage(t, server.URL)
+
+	if err := s.Put(context.Background(), "a.pdf", strings.NewReader("0123456789"), 4, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := fake.object("/uploads/a.pdf"); string(object.data) != "0123" {
+		t.Errorf("stored %q, want %q", object.data, "0123")
+	}
+}
+
+func TestS3StorageError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	s := newTestS3Storage(t, server.URL)
+
+	if err := s.Put(context.Background(), "a.pdf", strings.NewReader("data"), 4, "application/pdf"); err == nil {
+		t.Error("Put succeeded on a forbidden bucket")
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	tests := []struct {
+		opts S3Options
+		want string
+	}{
+		{S3Options{Region: "eu-west-1", Bucket: "uploads"}, "https://uploads.s3.eu-west-1.amazonaws.com/a.png"},
+		{S3Options{Endpoint: "http://localhost:9000/", Bucket: "uploads", ForcePathStyle: true}, "http://localhost:9000/uploads/a.png"},
+		{S3Options{Endpoint: "http://storage.local", Bucket: "uploads"}, "http://uploads.storage.local/a.png"},
+		{S3Options{Bucket: "uploads", BaseURL: "https://cdn.example.com/"}, "https://cdn.example.com/a.png"},
+	}
+	for _, tt := range tests {
+		tt.opts.Region = "eu-west-1"
+		s, err := NewS3Storage(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.URL("a.png"); got != tt.want {
+			t.Errorf("URL of %+v = %s, want %s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestNewS3StorageWithoutBucket(t *testing.T) {
+	if _, err := NewS3Storage(S3Options{Region: "us-east-1"}); err == nil {
+		t.Error("created a storage without bucket")
+	}
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Storage keeps uploaded files under a key and tells the URL they are served
+// from. Keys use slashes whatever the backend.
+type Storage interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// File is an uploaded file before it is stored. ContentType is the type sent
+// by the client, Validate detects the real one.
+type File struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
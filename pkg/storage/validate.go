@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+)
+
+// sniffLength is how many bytes http.DetectContentType looks at.
+const sniffLength = 512
+
+// AllowedTypes are the content types accepted as attachments with the file
+// extension they are stored with. Attachments are stored unchanged, images
+// are uploaded with uploadImage instead, which strips their EXIF metadata.
+var AllowedTypes = map[string]string{
+	"video/mp4":       ".mp4",
+	"application/pdf": ".pdf",
+}
+
+// Validate checks the size of the file and detects its content type from
+// its first bytes rather than trusting the client. On success the file holds
+// the detected type and its content can be read again from the start.
+func Validate(f *File, maxSize int64) (string, error) {
+	if f.Size <= 0 {
+		return "", apperrors.Validation("File %s is empty", f.Filename)
+	}
+	if f.Size > maxSize {
+		return "", apperrors.Validation("File %s is larger than %d bytes", f.Filename, maxSize)
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f.Content, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if strings.HasPrefix(contentType, "image/") {
+		return "", apperrors.Validation("File %s is an image, upload it with uploadImage", f.Filename)
+	}
+	ext, ok := AllowedTypes[contentType]
+	if !ok {
+		return "", apperrors.Validation("File %s has unsupported type %s", f.Filename, contentType)
+	}
+	f.ContentType = contentType
+	f.Content = io.MultiReader(bytes.NewReader(head), f.Content)
+	return ext, nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		size    int64
+		ext     string
+		// empty when the file is valid
+		code apperrors.Code
+	}{
+		{"pdf", "%PDF-1.4 document", 0, ".pdf", ""},
+		{"jpeg", "\xFF\xD8\xFF\xE1 photo with exif", 0, "", apperrors.CodeValidation},
+		{"png", "\x89PNG\x0D\x0A\x1A\x0A image", 0, "", apperrors.CodeValidation},
+		{"html", "<html><script></script></html>", 0, "", apperrors.CodeValidation},
+		{"empty", "", 0, "", apperrors.CodeValidation},
+		{"too large", "%PDF-1.4 document", 1 << 20, "", apperrors.CodeValidation},
+	}
+	for _, tt := range tests {
+		size := tt.size
+		if size == 0 {
+			size = int64(len(tt.content))
+		}
+		f := &File{Filename: tt.name, ContentType: "application/pdf", Size: size, Content: strings.NewReader(tt.content)}
+		ext, err := Validate(f, 1024)
+		if tt.code != "" {
+			if apperrors.CodeOf(err) != tt.code {
+				t.Errorf("%s: error %v, want code %s", tt.name, err, tt.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if ext != tt.ext {
+			t.Errorf("%s: extension %s, want %s", tt.name, ext, tt.ext)
+		}
+		// the sniffed bytes are read again
+		if data, _ := ioutil.ReadAll(f.Content); string(data) != tt.content {
+			t.Errorf("%s: content %q, want %q", tt.name, data, tt.content)
+		}
+	}
+}
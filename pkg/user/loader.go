@@ -0,0 +1,131 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+)
+
+const (
+	// LoaderWait is how long a loader collects usernames before querying them.
+	LoaderWait = 2 * time.Millisecond
+	// LoaderMaxBatch bounds the number of usernames queried at once.
+	LoaderMaxBatch = 100
+)
+
+type loaderKey struct{}
+
+// UserLoader batches the lookups of users by username made while resolving
+// one operation into a single query, and keeps the users it found so each
+// one is only fetched once.
+type UserLoader struct {
+	repository UserRepository
+	mu         sync.Mutex
+	cache      map[string]*models.User
+	batch      *userBatch
+	// wait is LoaderWait, longer in tests so their batches do not depend on
+	// the scheduling
+	wait time.Duration
+}
+
+type userBatch struct {
+	usernames []string
+	users     map[string]*models.User
+	err       error
+	full      chan struct{}
+	done      chan struct{}
+}
+
+func NewUserLoader(repository UserRepository) *UserLoader {
+	return &UserLoader{
+		repository: repository,
+		wait:       LoaderWait,
+		cache:      map[string]*models.User{},
+	}
+}
+
+// WithUserLoader returns a copy of ctx carrying the loader.
+func WithUserLoader(ctx context.Context, loader *UserLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// ForLoaderContext finds the loader of the operation, nil when there is none.
+func ForLoaderContext(ctx context.Context) *UserLoader {
+	loader, _ := ctx.Value(loaderKey{}).(*UserLoader)
+	return loader
+}
+
+// Load returns the user having the username, nil when there is no such user.
+func (l *UserLoader) Load(ctx context.Context, username string) (*models.User, error) {
+	l.mu.Lock()
+	if user, ok := l.cache[username]; ok {
+		l.mu.Unlock()
+		return user, nil
+	}
+	if l.batch == nil {
+		l.batch = &userBatch{full: make(chan struct{}), done: make(chan struct{})}
+		go l.fetch(ctx, l.batch)
+	}
+	batch := l.batch
+	batch.usernames = append(batch.usernames, username)
+	if len(batch.usernames) >= LoaderMaxBatch {
+		// the next usernames go to a new batch, this one is fetched now
+		l.batch = nil
+		close(batch.full)
+	}
+	l.mu.Unlock()
+
+	<-batch.done
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.users[username], nil
+}
+
+func (l *UserLoader) fetch(ctx context.Context, batch *userBatch) {
+	timer := time.NewTimer(l.wait)
+	select {
+	case <-timer.C:
+	case <-batch.full:
+		timer.Stop()
+	case <-ctx.Done():
+		timer.Stop()
+	}
+
+	l.mu.Lock()
+	if l.batch == batch {
+		l.batch = nil
+	}
+	usernames := unique(batch.usernames)
+	l.mu.Unlock()
+
+	users, err := l.repository.GetListByUsernames(ctx, usernames)
+	batch.err = err
+	batch.users = make(map[string]*models.User, len(users))
+	for _, user := range users {
+		batch.users[user.Username] = user
+	}
+
+	if err == nil {
+		l.mu.Lock()
+		for _, username := range usernames {
+			l.cache[username] = batch.users[username]
+		}
+		l.mu.Unlock()
+	}
+	close(batch.done)
+}
+
+func unique(usernames []string) []string {
+	seen := make(map[string]bool, len(usernames))
+	result := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		if !seen[username] {
+			seen[username] = true
+			result = append(result, username)
+		}
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+)
+
+// countingRepository answers GetListByUsernames from a set of users and
+// records every query. The other methods are not used by the loader.
+type countingRepository struct {
+	UserRepository
+	users map[string]*models.User
+	err   error
+
+	lock    sync.Mutex
+	fetched [][]string
+}
+
+func newCountingRepository(usernames ...string) *countingRepository {
+	r := &countingRepository{users: map[string]*models.User{}}
+	for _, username := range usernames {
+		r.users[username] = &models.User{Username: username}
+	}
+	return r
+}
+
+func (r *countingRepository) GetListByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	r.lock.Lock()
+	r.fetched = append(r.fetched, append([]string{}, usernames...))
+	r.lock.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	users := []*models.User{}
+	for _, username := range usernames {
+		if user, ok := r.users[username]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *countingRepository) queries() [][]string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.fetched
+}
+
+// newTestLoader waits long enough for all the loads of a test to join the
+// same batch.
+func newTestLoader(r UserRepository, wait time.Duration) *UserLoader {
+	l := NewUserLoader(r)
+	l.wait = wait
+	return l
+}
+
+type loadResult struct {
+	username string
+	user     *models.User
+	err      error
+}
+
+// loadAll loads the usernames concurrently.
+func loadAll(l *UserLoader, usernames []string) []loadResult {
+	results := make([]loadResult, len(usernames))
+	var wg sync.WaitGroup
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			user, err := l.Load(context.Background(), username)
+			results[i] = loadResult{username, user, err}
+		}(i, username)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestUserLoaderBatches(t *testing.T) {
+	r := newCountingRepository("alice", "bob")
+	l := newTestLoader(r, 50*time.Millisecond)
+
+	usernames := []string{"alice", "bob", "ghost", "alice", "bob", "ghost", "alice"}
+	for _, result := range loadAll(l, usernames) {
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		switch {
+		case result.username == "ghost" && result.user != nil:
+			t.Errorf("missing user loaded as %+v", result.user)
+		case result.username != "ghost" && (result.user == nil || result.user.Username != result.username):
+			t.Errorf("%s loaded as %+v", result.username, result.user)
+		}
+	}
+
+	queries := r.queries()
+	if len(queries) != 1 {
+		t.Fatalf("%d queries, want 1", len(queries))
+	}
+	sort.Strings(queries[0])
+	if fmt.Sprint(queries[0]) != "[alice bob ghost]" {
+		t.Errorf("queried %v, want each username once", queries[0])
+	}
+
+	// the users found and the missing ones are not queried again
+	for _, result := range loadAll(l, []string{"alice", "ghost"}) {
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+	}
+	if len(r.queries()) != 1 {
+		t.Errorf("%d queries after loading cached users, want 1", len(r.queries()))
+	}
+}
+
+func TestUserLoaderMaxBatch(t *testing.T) {
+	r := newCountingRepository()
+	// a full batch is fetched without waiting
+	l := newTestLoader(r, time.Hour)
+
+	usernames := make([]string, LoaderMaxBatch)
+	for i := range usernames {
+		usernames[i] = fmt.Sprintf("user%d", i)
+	}
+	done := make(chan []loadResult)
+	go func() { done <- loadAll(l, usernames) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("full batch not fetched")
+	}
+
+	queries := r.queries()
+	if len(queries) != 1 || len(queries[0]) != LoaderMaxBatch {
+		t.Errorf("%d queries, want 1 of %d usernames", len(queries), LoaderMaxBatch)
+	}
+}
+
+func TestUserLoaderSplitsBatches(t *testing.T) {
+	r := newCountingRepository()
+	l := newTestLoader(r, 50*time.Millisecond)
+
+	usernames := make([]string, LoaderMaxBatch+10)
+	for i := range usernames {
+		usernames[i] = fmt.Sprintf("user%d", i)
+	}
+	loadAll(l, usernames)
+
+	queries := r.queries()
+	if len(queries) != 2 {
+		t.Fatalf("%d queries, want 2", len(queries))
+	}
+	sizes := []int{len(queries[0]), len(queries[1])}
+	sort.Ints(sizes)
+	if sizes[0] != 10 || sizes[1] != LoaderMaxBatch {
+		t.Errorf("batches of %v usernames, want 10 and %d", sizes, LoaderMaxBatch)
+	}
+}
+
+func TestUserLoaderError(t *testing.T) {
+	r := newCountingRepository("alice")
+	r.err = errors.New("connection lost")
+	l := newTestLoader(r, 50*time.Millisecond)
+
+	for _, result := range loadAll(l, []string{"alice", "bob"}) {
+		if result.err != r.err {
+			t.Errorf("%s: error %v, want %v", result.username, result.err, r.err)
+		}
+	}
+
+	// failures are not cached
+	r.err = nil
+	user, err := l.Load(context.Background(), "alice")
+	if err != nil || user == nil || user.Username != "alice" {
+		t.Errorf("loaded %+v, %v after the error", user, err)
+	}
+	if len(r.queries()) != 2 {
+		t.Errorf("%d queries, want 2", len(r.queries()))
+	}
+}
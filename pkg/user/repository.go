@@ -22,6 +22,9 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) (*models.User, error)
 	GetList(ctx context.Context) ([]*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetListByUsernames(ctx context.Context, usernames []string) ([]*models.User, error)
+	UpdateRole(ctx context.Context, username string, role models.Role) (*models.User, error)
+	Search(ctx context.Context, query string, limit int) ([]*models.User, error)
 }
 
 type repository struct {
@@ -45,6 +48,11 @@ func NewUserRepository(db *mongo.Database, log *logger.AppLog) UserRepository {
 			// create UniqueIndex option
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			// full text search over usernames, emails are left out so they
+			// cannot be probed through search
+			Keys: bson.D{{Key: "username", Value: "text"}},
+		},
 	}
 
 	ctx := context.Background()
@@ -91,3 +99,52 @@ func (r *repository) GetByUsername(ctx context.Context, username string) (*model
 	}
 	return user, nil
 }
+
+func (r *repository) GetListByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	users := []*models.User{}
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"username": bson.M{"$in": usernames}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Search returns the users matching the text query, most relevant first.
+func (r *repository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	users := []*models.User{}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"$text": bson.M{"$search": query}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *repository) UpdateRole(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	user := &models.User{}
+	update := bson.M{"$set": bson.M{"role": role}}
+	err := r.Collection.FindOneAndUpdate(ctx, bson.M{"username": username}, update, options.FindOneAndUpdate().SetReturnDocument(1)).Decode(user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -2,69 +2,186 @@ package user
 
 import (
 	"context"
-	"fmt"
-	"github.com/trinhdaiphuc/social-network/config"
 	"github.com/trinhdaiphuc/social-network/internal"
 	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
+	"github.com/trinhdaiphuc/social-network/pkg/lockout"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/session"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
 )
 
 type UserService interface {
 	Register(ctx context.Context, user *models.User) (*models.User, error)
-	Login(ctx context.Context, user *models.User) (*models.User, error)
+	Login(ctx context.Context, user *models.User, clientIP string) (*models.User, error)
 	GetUsers(ctx context.Context) ([]*models.User, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*models.User, error)
+	Logout(ctx context.Context, sessionID primitive.ObjectID) error
+	LogoutAllSessions(ctx context.Context, userID primitive.ObjectID) (int, error)
+	SetRole(ctx context.Context, username string, role models.Role) (*models.User, error)
+	Unlock(ctx context.Context, username string) (*models.User, error)
+	EnsureAdmin(ctx context.Context, username string) (*models.User, error)
 }
 
 type service struct {
 	repository UserRepository
+	sessions   session.SessionService
+	lockout    lockout.LockoutService
 	Logger     *logger.AppLog
 }
 
+var (
+	// dummyHash is compared with the password of unknown usernames so they
+	// take as long to reject as wrong passwords.
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
 func NewUserService(db *mongo.Database, log *logger.AppLog) UserService {
 	r := NewUserRepository(db, log)
-	return &service{repository: r, Logger: log}
+	return &service{
+		repository: r,
+		sessions:   session.NewSessionService(db, log),
+		lockout:    lockout.NewLockoutService(db, log),
+		Logger:     log,
+	}
 }
 
 func (s *service) Register(ctx context.Context, user *models.User) (*models.User, error) {
 	// Hash password
 	user.Password = internal.HashPassword(user.Password)
+	user.Role = models.RoleUser
 
 	// Create user
 	user, err := s.repository.Create(ctx, user)
 	if err != nil {
 		s.Logger.Errorf("Register error %#v", err)
-		if writeErr, ok := err.(mongo.WriteException); ok {
-			if writeErr.WriteErrors[0].Code == 11000 {
-				return nil, fmt.Errorf("Your account email or username is already taken.")
-			}
+		if internal.IsDuplicateKeyError(err) {
+			return nil, apperrors.Conflict("Your account email or username is already taken.")
 		}
 		return nil, err
 	}
 
-	// Create token
-	user.Token, err = internal.CreateTokenWithUser(config.GetConfig().JwtKey, user, 5)
+	// Create tokens
+	if err := s.sessions.Start(ctx, user); err != nil {
+		s.Logger.Errorf("Start session error %#v", err)
+		return nil, err
+	}
 	return user, nil
 }
 
-func (s *service) Login(ctx context.Context, user *models.User) (*models.User, error) {
+// Login fails the same way whether the username or the password is wrong so
+// it does not tell which usernames exist.
+func (s *service) Login(ctx context.Context, user *models.User, clientIP string) (*models.User, error) {
+	if err := s.lockout.Reserve(ctx, user.Username, clientIP); err != nil {
+		return nil, err
+	}
+
 	// Get user by username
 	getUser, err := s.repository.GetByUsername(ctx, user.Username)
-	if err != nil {
-		s.Logger.Errorf("Register error %#v", err)
-		return nil, fmt.Errorf("Not found user")
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.Logger.Errorf("Login error %#v", err)
+		return nil, err
 	}
 
 	// Check user password
-	if ok := internal.CheckPasswordHash(user.Password, getUser.Password); !ok {
-		return nil, fmt.Errorf("Invalid password")
+	hash := getDummyHash()
+	if getUser != nil {
+		hash = getUser.Password
+	}
+	if ok := internal.CheckPasswordHash(user.Password, hash); !ok || getUser == nil {
+		s.lockout.Fail(ctx, user.Username, clientIP)
+		return nil, apperrors.Unauthenticated("Invalid credentials")
 	}
+	s.lockout.Succeed(ctx, user.Username, clientIP)
 
-	// Create token
-	getUser.Token, err = internal.CreateTokenWithUser(config.GetConfig().JwtKey, getUser, 12*60)
+	// Create tokens
+	if err := s.sessions.Start(ctx, getUser); err != nil {
+		s.Logger.Errorf("Start session error %#v", err)
+		return nil, err
+	}
 	return getUser, nil
 }
 
+func getDummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash = internal.HashPassword("dummy password")
+	})
+	return dummyHash
+}
+
 func (s *service) GetUsers(ctx context.Context) ([]*models.User, error) {
 	return s.repository.GetList(ctx)
 }
+
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (*models.User, error) {
+	sess, newRefreshToken, err := s.sessions.Rotate(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	getUser, err := s.repository.GetByUsername(ctx, sess.Username)
+	if err != nil {
+		s.Logger.Errorf("Refresh token error %#v", err)
+		return nil, apperrors.NotFound("Not found user")
+	}
+
+	getUser.Token, err = s.sessions.AccessToken(getUser, sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	getUser.RefreshToken = newRefreshToken
+	return getUser, nil
+}
+
+func (s *service) Logout(ctx context.Context, sessionID primitive.ObjectID) error {
+	return s.sessions.Revoke(ctx, sessionID)
+}
+
+func (s *service) LogoutAllSessions(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	return s.sessions.RevokeAll(ctx, userID)
+}
+
+// SetRole changes the role of a user. The new role is applied to the user's
+// tokens issued from now on, so all sessions are logged out.
+func (s *service) SetRole(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	user, err := s.repository.UpdateRole(ctx, username, role)
+	if err != nil {
+		s.Logger.Errorf("Set role error %#v", err)
+		return nil, apperrors.NotFound("Not found user")
+	}
+	if _, err := s.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Unlock lets the user log in again at once after failed logins.
+func (s *service) Unlock(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.repository.GetByUsername(ctx, username)
+	if err != nil {
+		s.Logger.Errorf("Unlock error %#v", err)
+		return nil, apperrors.NotFound("Not found user")
+	}
+	if _, err := s.lockout.Reset(ctx, username); err != nil {
+		s.Logger.Errorf("Unlock error %#v", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// EnsureAdmin makes the registered user an ADMIN unless it already is one. It
+// creates the first admin, who can then grant roles with the setUserRole mutation.
+func (s *service) EnsureAdmin(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.repository.GetByUsername(ctx, username)
+	if err != nil {
+		s.Logger.Errorf("Ensure admin error %#v", err)
+		return nil, apperrors.NotFound("Not found user")
+	}
+	if user.HasRole(models.RoleAdmin) {
+		return user, nil
+	}
+	return s.SetRole(ctx, username, models.RoleAdmin)
+}
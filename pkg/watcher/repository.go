@@ -0,0 +1,83 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "resume_tokens"
+
+	// tokenTTL removes the tokens of replicas that are gone, a token this
+	// old is past the oplog anyway
+	tokenTTL = 7 * 24 * time.Hour
+)
+
+type resumeToken struct {
+	Stream    string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// ResumeTokenRepository stores the position of change streams so a restarted
+// watcher continues where it stopped.
+type ResumeTokenRepository interface {
+	Get(ctx context.Context, stream string) (bson.Raw, error)
+	Save(ctx context.Context, stream string, token bson.Raw) error
+	Delete(ctx context.Context, stream string) error
+}
+
+type repository struct {
+	Collection *mongo.Collection
+	Logger     *logger.AppLog
+}
+
+func NewResumeTokenRepository(db *mongo.Database, log *logger.AppLog) ResumeTokenRepository {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.M{
+				"updatedAt": 1,
+			},
+			Options: options.Index().SetExpireAfterSeconds(int32(tokenTTL.Seconds())),
+		},
+	}
+
+	ctx := context.Background()
+	tokenCollection := db.Collection(collectionName)
+	tokenCollection.Indexes().CreateMany(ctx, mod)
+	return &repository{
+		Collection: tokenCollection,
+		Logger:     log,
+	}
+}
+
+// Get returns the saved token of the stream or nil when it has none.
+func (r *repository) Get(ctx context.Context, stream string) (bson.Raw, error) {
+	token := &resumeToken{}
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": stream}).Decode(token); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token.Token, nil
+}
+
+func (r *repository) Save(ctx context.Context, stream string, token bson.Raw) error {
+	update := bson.M{"$set": bson.M{
+		"token":     token,
+		"updatedAt": time.Now(),
+	}}
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": stream}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, stream string) error {
+	_, err := r.Collection.DeleteOne(ctx, bson.M{"_id": stream})
+	return err
+}
@@ -0,0 +1,146 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/trinhdaiphuc/social-network/config"
+	"github.com/trinhdaiphuc/social-network/internal/logger"
+	"github.com/trinhdaiphuc/social-network/pkg/models"
+	"github.com/trinhdaiphuc/social-network/pkg/pubsub"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	postStream = "posts"
+
+	// the resume token is older than the oplog, the stream must start over
+	changeStreamHistoryLost = 286
+
+	retryDelay = 5 * time.Second
+)
+
+// Watcher turns the changes of a collection into subscription events.
+type Watcher interface {
+	Run(ctx context.Context)
+}
+
+type postWatcher struct {
+	// stream names the resume token of this replica
+	stream     string
+	collection *mongo.Collection
+	tokens     ResumeTokenRepository
+	broker     pubsub.Broker
+	Logger     *logger.AppLog
+}
+
+type postChange struct {
+	OperationType string       `bson:"operationType"`
+	FullDocument  *models.Post `bson:"fullDocument"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// NewPostWatcher watches the posts collection, so posts written by scripts or
+// other services reach subscribers too. Change streams need MongoDB to run as
+// a replica set. Every replica resumes from its own token, a shared one would
+// make a restarted replica skip or replay the events of another.
+func NewPostWatcher(db *mongo.Database, broker pubsub.Broker, log *logger.AppLog) Watcher {
+	return &postWatcher{
+		stream:     postStream + ":" + replicaID(log),
+		collection: db.Collection("posts"),
+		tokens:     NewResumeTokenRepository(db, log),
+		broker:     broker,
+		Logger:     log,
+	}
+}
+
+// Run publishes the post changes until ctx is done, reopening the change
+// stream after errors.
+func (w *postWatcher) Run(ctx context.Context) {
+	for {
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		w.Logger.Errorf("Watch posts error %#v", err)
+
+		if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Code == changeStreamHistoryLost {
+			w.Logger.Warnf("Resume token of %s is lost, events written meanwhile are skipped", w.stream)
+			if err := w.tokens.Delete(ctx, w.stream); err != nil {
+				w.Logger.Errorf("Delete resume token error %#v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (w *postWatcher) watch(ctx context.Context) error {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	token, err := w.tokens.Get(ctx, w.stream)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	stream, err := w.collection.Watch(ctx, mongo.Pipeline{}, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		change := &postChange{}
+		if err := stream.Decode(change); err != nil {
+			w.Logger.Errorf("Decode post change error %#v", err)
+		} else {
+			w.publish(change)
+		}
+		if err := w.tokens.Save(ctx, w.stream, stream.ResumeToken()); err != nil {
+			w.Logger.Errorf("Save resume token error %#v", err)
+		}
+	}
+	return stream.Err()
+}
+
+// publish delivers the change to the subscribers of this replica only, every
+// replica runs its own watcher.
+func (w *postWatcher) publish(change *postChange) {
+	id := change.DocumentKey.ID.Hex()
+	switch change.OperationType {
+	case "insert":
+		w.broker.PublishLocal(pubsub.TopicNewPost, change.FullDocument)
+	case "update", "replace":
+		// the post may be deleted before its update is looked up
+		if change.FullDocument != nil {
+			w.broker.PublishLocal(pubsub.PostTopic(pubsub.TopicPostUpdated, id), change.FullDocument)
+		}
+	case "delete":
+		w.broker.PublishLocal(pubsub.TopicPostDeleted, id)
+	}
+}
+
+// replicaID identifies this replica across restarts, WATCHER_ID or else the
+// hostname, which is stable for a StatefulSet pod or a Compose service.
+func replicaID(log *logger.AppLog) string {
+	if id := config.GetConfig().WatcherID; id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		log.Warnf("Get hostname error %v, set WATCHER_ID", err)
+		return "default"
+	}
+	return host
+}
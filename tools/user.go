@@ -2,8 +2,8 @@ package tools
 
 import (
 	"context"
-	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/trinhdaiphuc/social-network/pkg/apperrors"
 	"github.com/trinhdaiphuc/social-network/pkg/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -12,21 +12,53 @@ import (
 func ForUserContext(ctx context.Context) (*models.User, error) {
 	claims, ok := ctx.Value("user").(jwt.MapClaims)
 	if !ok {
-		return nil, fmt.Errorf("not found user context")
+		return nil, apperrors.Unauthenticated("Not found user context")
 	}
 
 	userClaim, ok := claims["user"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("Parse token error", claims)
+		return nil, apperrors.Unauthenticated("Invalid token")
 	}
 	id, _ := userClaim["id"].(string)
 	oid, _ := primitive.ObjectIDFromHex(id)
 	email, _ := userClaim["email"].(string)
 	userName, _ := userClaim["username"].(string)
+	role, _ := userClaim["role"].(string)
 	user := &models.User{
 		ID:       oid,
 		Email:    email,
 		Username: userName,
+		Role:     models.Role(role),
 	}
 	return user, nil
 }
+
+// ForSessionContext finds the session id of the access token. REQUIRES Middleware to have run.
+func ForSessionContext(ctx context.Context) (primitive.ObjectID, error) {
+	claims, ok := ctx.Value("user").(jwt.MapClaims)
+	if !ok {
+		return primitive.NilObjectID, apperrors.Unauthenticated("Not found user context")
+	}
+
+	sid, _ := claims["sid"].(string)
+	oid, err := primitive.ObjectIDFromHex(sid)
+	if err != nil {
+		return primitive.NilObjectID, apperrors.Unauthenticated("Not found session")
+	}
+	return oid, nil
+}
+
+// ForRefreshTokenContext finds the refresh token sent in the Refresh-Token header. REQUIRES Middleware to have run.
+func ForRefreshTokenContext(ctx context.Context) (string, error) {
+	refreshToken, ok := ctx.Value("refreshToken").(string)
+	if !ok || refreshToken == "" {
+		return "", apperrors.Unauthenticated("Not found refresh token")
+	}
+	return refreshToken, nil
+}
+
+// ForClientIPContext finds the IP address of the client. REQUIRES Middleware to have run.
+func ForClientIPContext(ctx context.Context) string {
+	ip, _ := ctx.Value("clientIP").(string)
+	return ip
+}
@@ -0,0 +1,44 @@
+// Generates the manifest of the operations the web client sends, for the
+// server's PERSISTED_QUERIES_ONLY mode. Each gql document of src is printed
+// the way Apollo Client sends it, with __typename added, and keyed by the
+// SHA-256 hash of that text.
+//
+// Usage: node scripts/persisted-queries.js [output], from the web directory.
+const crypto = require("crypto")
+const fs = require("fs")
+const path = require("path")
+const {parse, print} = require("graphql")
+const {addTypenameToDocument} = require("@apollo/client/utilities")
+
+const srcDir = path.join(__dirname, "..", "src")
+const output = process.argv[2] || path.join(__dirname, "..", "persisted-queries.json")
+
+const sourceFiles = (dir) =>
+    fs.readdirSync(dir, {withFileTypes: true}).flatMap((entry) => {
+        const file = path.join(dir, entry.name)
+        if (entry.isDirectory()) {
+            return sourceFiles(file)
+        }
+        return /\.(js|jsx|ts|tsx)$/.test(entry.name) ? [file] : []
+    })
+
+const manifest = {}
+for (const file of sourceFiles(srcDir)) {
+    const source = fs.readFileSync(file, "utf8")
+    for (const match of source.matchAll(/gql`([^`]*)`/g)) {
+        if (match[1].includes("${")) {
+            console.warn(`${file}: skipped a document with interpolations`)
+            continue
+        }
+        const query = print(addTypenameToDocument(parse(match[1])))
+        const hash = crypto.createHash("sha256").update(query).digest("hex")
+        manifest[hash] = query
+    }
+}
+
+const sorted = {}
+for (const hash of Object.keys(manifest).sort()) {
+    sorted[hash] = manifest[hash]
+}
+fs.writeFileSync(output, JSON.stringify(sorted, null, 2) + "\n")
+console.log(`Wrote ${Object.keys(sorted).length} operations to ${output}`)
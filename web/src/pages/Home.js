@@ -6,20 +6,33 @@ import PostCard from "../components/PostCard"
 const FETCH_POST_QUERY = gql`
 {
      getPosts {
-        id
-        body
-        createdAt
-        username
-        likeCount
-        likes {
-            username  
-        }  
-        commentCount 
-        comments {
-            id
-            username
-            createdAt
-            body
+        edges {
+            cursor
+            node {
+                id
+                body
+                createdAt
+                username
+                likeCount
+                likes {
+                    username  
+                }  
+                commentCount 
+                comments(first: 3) {
+                    edges {
+                        node {
+                            id
+                            username
+                            createdAt
+                            body
+                        }
+                    }
+                }
+            }
+        }
+        pageInfo {
+            hasNextPage
+            endCursor
         }
     }
 }
@@ -36,7 +49,7 @@ const Home = () => {
                 {loading ? (
                     <h1>Loading Posts...</h1>
                 ) : (
-                    data && data.getPosts && data.getPosts.map(post => (
+                    data && data.getPosts && data.getPosts.edges.map(({node: post}) => (
                         <Grid.Column key={post.id} className="post">
                             <PostCard post={post}/>
                         </Grid.Column>